@@ -4,9 +4,10 @@ import "context"
 
 // Item :
 type Item struct {
-	Key string
-	Val []byte
-	TTL int64 // in seconds
+	Key  string
+	Val  []byte
+	TTL  int64    // in seconds
+	Tags []string // tags to invalidate item by, if cache supports it
 }
 
 // Cache :
@@ -25,10 +26,25 @@ type Cache interface {
 	Delete(context.Context, string) error
 
 	// MultiGet : Get multiple keys
-	//   Maximum of maxCacheListSize is accepted
+	//   []*Item: aligned with keys, nil for a missing key
 	MultiGet(context.Context, []string) ([]*Item, error)
 
+	// MultiPut : put multiple records, upsert operation
+	//   []*Item: records data
+	MultiPut(context.Context, []*Item) error
+
 	// MultiDelete : MultiDelete multiple keys
-	//   Maximum of maxCacheListSize is accepted
 	MultiDelete(context.Context, []string) error
 }
+
+// Invalidator : cache which can delete items by tag or key prefix
+type Invalidator interface {
+
+	// InvalidateTag : delete all items put with tag
+	//   string: tag
+	InvalidateTag(context.Context, string) error
+
+	// InvalidatePrefix : delete all items whose key starts with prefix
+	//   string: key prefix
+	InvalidatePrefix(context.Context, string) error
+}
@@ -0,0 +1,249 @@
+// Package cachetest : behavioural test suite which every cache.Cache
+// implementation should pass
+package cachetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alokic/gopkg/cache"
+)
+
+// NewCache : returns an empty cache for every test
+type NewCache func() cache.Cache
+
+// Run : runs the suite on caches returned by newCache
+func Run(t *testing.T, newCache NewCache) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, cache.Cache)
+	}{
+		{"PutGet", testPutGet},
+		{"GetNil", testGetNil},
+		{"TTL", testTTL},
+		{"NoTTL", testNoTTL},
+		{"Delete", testDelete},
+		{"MultiGet", testMultiGet},
+		{"MultiGetNoDeletedItem", testMultiGetNoDeletedItem},
+		{"MultiGetMissing", testMultiGetMissing},
+		{"MultiPut", testMultiPut},
+		{"MultiDelete", testMultiDelete},
+		{"ManyKeys", testManyKeys},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newCache())
+		})
+	}
+}
+
+func load(t *testing.T, c cache.Cache, n int) {
+	for i := 1; i <= n; i++ {
+		item := &cache.Item{
+			Key: fmt.Sprintf("%d", i),
+			Val: []byte(fmt.Sprintf("val%d", i)),
+			TTL: 60,
+		}
+		if _, err := c.Put(context.Background(), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func keys(n int) []string {
+	var k []string
+	for i := 1; i <= n; i++ {
+		k = append(k, fmt.Sprintf("%d", i))
+	}
+	return k
+}
+
+func testPutGet(t *testing.T, c cache.Cache) {
+	load(t, c, 10)
+
+	r, err := c.Get(context.Background(), "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r == nil || r.Key != "7" || string(r.Val) != "val7" {
+		t.Errorf("Error: PutGet Got %v, Want %s", r, "val7")
+	}
+}
+
+func testGetNil(t *testing.T, c cache.Cache) {
+	load(t, c, 2)
+
+	r, err := c.Get(context.Background(), "notexist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r != nil {
+		t.Errorf("Error: GetNil Got %v, Want nil", r)
+	}
+}
+
+func testTTL(t *testing.T, c cache.Cache) {
+	load(t, c, 1)
+
+	r, err := c.Get(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r == nil || r.TTL <= 0 || r.TTL > 60 {
+		t.Errorf("Error: TTL Got %v, Want TTL in (0, 60]", r)
+	}
+}
+
+func testNoTTL(t *testing.T, c cache.Cache) {
+	for _, ttl := range []int64{0, -1} {
+		item := &cache.Item{Key: "1", Val: []byte("val1"), TTL: ttl}
+		if _, err := c.Put(context.Background(), item); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := c.Get(context.Background(), "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r == nil || r.TTL != -1 {
+			t.Errorf("Error: NoTTL Got %v for TTL %d, Want never expiring item", r, ttl)
+		}
+	}
+}
+
+func testDelete(t *testing.T, c cache.Cache) {
+	load(t, c, 2)
+
+	if err := c.Delete(context.Background(), "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.Get(context.Background(), "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r != nil {
+		t.Errorf("Error: Delete Got %v, Want nil", r)
+	}
+}
+
+func testMultiGet(t *testing.T, c cache.Cache) {
+	load(t, c, 3)
+
+	r, err := c.MultiGet(context.Background(), []string{"3", "1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != 3 || r[0] == nil || r[0].Key != "3" || r[1] == nil || r[1].Key != "1" || r[2] == nil || r[2].Key != "2" {
+		t.Errorf("Error: MultiGet Got %v, Want items aligned with keys", r)
+	}
+}
+
+func testMultiGetNoDeletedItem(t *testing.T, c cache.Cache) {
+	load(t, c, 3)
+
+	if err := c.Delete(context.Background(), "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.MultiGet(context.Background(), keys(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != 3 || r[0] == nil || r[1] != nil || r[2] == nil {
+		t.Errorf("Error: MultiGetNoDeletedItem Got %v, Want nil for deleted item", r)
+	}
+}
+
+func testMultiGetMissing(t *testing.T, c cache.Cache) {
+	load(t, c, 1)
+
+	r, err := c.MultiGet(context.Background(), []string{"notexist", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != 2 || r[0] != nil || r[1] == nil || r[1].Key != "1" {
+		t.Errorf("Error: MultiGetMissing Got %v, Want nil for missing item", r)
+	}
+}
+
+func testMultiPut(t *testing.T, c cache.Cache) {
+	items := []*cache.Item{
+		{Key: "1", Val: []byte("val1"), TTL: 60},
+		{Key: "2", Val: []byte("val2"), TTL: 60},
+	}
+	if err := c.MultiPut(context.Background(), items); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.MultiGet(context.Background(), keys(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != 2 || r[0] == nil || string(r[0].Val) != "val1" || r[1] == nil || string(r[1].Val) != "val2" {
+		t.Errorf("Error: MultiPut Got %v, Want put items", r)
+	}
+}
+
+func testManyKeys(t *testing.T, c cache.Cache) {
+	n := 100
+	load(t, c, n)
+
+	r, err := c.MultiGet(context.Background(), keys(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != n {
+		t.Fatalf("Error: ManyKeys Got %d items, Want %d", len(r), n)
+	}
+	for i, v := range r {
+		if v == nil || v.Key != fmt.Sprintf("%d", i+1) {
+			t.Errorf("Error: ManyKeys Got %v at %d, Want %d", v, i, i+1)
+			return
+		}
+	}
+
+	if err := c.MultiDelete(context.Background(), keys(n)); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, _ := c.Get(context.Background(), fmt.Sprintf("%d", n)); r != nil {
+		t.Errorf("Error: ManyKeys Got %v, Want nil", r)
+	}
+}
+
+func testMultiDelete(t *testing.T, c cache.Cache) {
+	load(t, c, 3)
+
+	if err := c.MultiDelete(context.Background(), []string{"2", "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.Get(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.Key != "1" {
+		t.Errorf("Error: MultiDelete Got %v, Want %s", r, "1")
+	}
+
+	r, err = c.Get(context.Background(), "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != nil {
+		t.Errorf("Error: MultiDelete Got %v, Want nil", r)
+	}
+}
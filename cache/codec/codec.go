@@ -0,0 +1,80 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// ErrCodecUnsupportedType : value can not be serialized by codec
+	ErrCodecUnsupportedType = errors.New("Codec: unsupported type")
+
+	// ErrCodecUnknownTag : item was written with a codec or compression which is not registered
+	ErrCodecUnknownTag = errors.New("Codec: unknown tag")
+
+	// ErrCodecShortValue : item is too short to carry the header
+	ErrCodecShortValue = errors.New("Codec: value too short")
+
+	// ErrCodecZstdUnsupported : zstd is built only with cgo
+	ErrCodecZstdUnsupported = errors.New("Codec: zstd needs cgo")
+)
+
+// Tag : identifies codec of an item, stored next to the value. Tags of
+// built-in codecs must never change, so that old items can still be read
+type Tag byte
+
+const (
+	// TagJSON :
+	TagJSON Tag = iota + 1
+	// TagMsgpack :
+	TagMsgpack
+	// TagProto :
+	TagProto
+	// TagGob :
+	TagGob
+)
+
+// Codec : serializes values to item bytes
+type Codec interface {
+	Tag() Tag
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSON : encoding/json codec
+type JSON struct{}
+
+// Tag :
+func (JSON) Tag() Tag { return TagJSON }
+
+// Marshal :
+func (JSON) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal :
+func (JSON) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Gob : encoding/gob codec
+type Gob struct{}
+
+// Tag :
+func (Gob) Tag() Tag { return TagGob }
+
+// Marshal :
+func (Gob) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Unmarshal :
+func (Gob) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
@@ -0,0 +1,164 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/alokic/gopkg/cache"
+	"github.com/alokic/gopkg/cache/memory"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/tinylib/msgp/msgp"
+)
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+// MarshalMsg : as generated by msgp
+func (u *testUser) MarshalMsg(b []byte) ([]byte, error) {
+	b = msgp.AppendArrayHeader(b, 2)
+	b = msgp.AppendString(b, u.Name)
+	return msgp.AppendInt(b, u.Age), nil
+}
+
+// UnmarshalMsg : as generated by msgp
+func (u *testUser) UnmarshalMsg(b []byte) ([]byte, error) {
+	var err error
+	if _, b, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+		return b, err
+	}
+	if u.Name, b, err = msgp.ReadStringBytes(b); err != nil {
+		return b, err
+	}
+	u.Age, b, err = msgp.ReadIntBytes(b)
+	return b, err
+}
+
+// Marshal : as generated by protobuf
+func (u *testUser) Marshal() ([]byte, error) {
+	return append([]byte{byte(u.Age)}, u.Name...), nil
+}
+
+// Unmarshal : as generated by protobuf
+func (u *testUser) Unmarshal(b []byte) error {
+	u.Age, u.Name = int(b[0]), string(b[1:])
+	return nil
+}
+
+// testCompressions : every compression of this build
+func testCompressions() []Compression {
+	if zstdEnabled {
+		return []Compression{None, Snappy, Zstd}
+	}
+	return []Compression{None, Snappy}
+}
+
+func testTypedCache(t *testing.T, c cache.Cache, b *TypedCacheBuilder) *TypedCache {
+	tc, err := b.SetCache(c).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+func testMemoryCache(t *testing.T) cache.Cache {
+	c, err := memory.NewCacheBuilder().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestTypedCacheCodecs(t *testing.T) {
+	for _, codec := range []Codec{JSON{}, Msgpack{}, Proto{}, Gob{}} {
+		for _, comp := range testCompressions() {
+			tc := testTypedCache(t, testMemoryCache(t), NewTypedCacheBuilder().SetCodec(codec).SetCompression(comp, 0))
+
+			if err := tc.Put(context.Background(), "1", &testUser{Name: "alice", Age: 30}, 60); err != nil {
+				t.Errorf("Error: TestTypedCacheCodecs %T %d: %v", codec, comp, err)
+				continue
+			}
+
+			var u testUser
+			ok, err := tc.Get(context.Background(), "1", &u)
+			if err != nil || !ok || u.Name != "alice" || u.Age != 30 {
+				t.Errorf("Error: TestTypedCacheCodecs %T %d Got %v %v %v, Want %v", codec, comp, u, ok, err, testUser{"alice", 30})
+			}
+		}
+	}
+}
+
+func TestTypedCacheCompressionThreshold(t *testing.T) {
+	tc := testTypedCache(t, testMemoryCache(t), NewTypedCacheBuilder().SetCompression(Snappy, 100))
+
+	small, _ := tc.Encode("small")
+	large, _ := tc.Encode(string(bytes.Repeat([]byte("a"), 1000)))
+
+	if Compression(small[1]) != None || Compression(large[1]) != Snappy || len(large) >= 1000 {
+		t.Errorf("Error: TestTypedCacheCompressionThreshold Got %d %d, Want %d %d", small[1], large[1], None, Snappy)
+	}
+}
+
+func TestTypedCacheCodecChange(t *testing.T) {
+	c := testMemoryCache(t)
+
+	old := testTypedCache(t, c, NewTypedCacheBuilder().SetCodec(JSON{}))
+	old.Put(context.Background(), "1", &testUser{Name: "bob", Age: 40}, 60)
+
+	// items written with JSON are still read after switching to gob and zstd
+	comps := testCompressions()
+	tc := testTypedCache(t, c, NewTypedCacheBuilder().SetCodec(Gob{}).SetCompression(comps[len(comps)-1], 0))
+
+	var u testUser
+	ok, err := tc.Get(context.Background(), "1", &u)
+	if err != nil || !ok || u.Name != "bob" {
+		t.Errorf("Error: TestTypedCacheCodecChange Got %v %v %v, Want %s", u, ok, err, "bob")
+	}
+
+	ok, err = tc.Get(context.Background(), "notexist", &u)
+	if err != nil || ok {
+		t.Errorf("Error: TestTypedCacheCodecChange Got %v %v, Want not found", ok, err)
+	}
+}
+
+func TestTypedCacheErrors(t *testing.T) {
+	tc := testTypedCache(t, testMemoryCache(t), NewTypedCacheBuilder().SetCodec(Msgpack{}))
+
+	if err := tc.Put(context.Background(), "1", "not generated", 60); err != ErrCodecUnsupportedType {
+		t.Errorf("Error: TestTypedCacheErrors Got %v, Want %v", err, ErrCodecUnsupportedType)
+	}
+
+	var u testUser
+	if err := tc.Decode([]byte{99, 0, 1}, &u); err != ErrCodecUnknownTag {
+		t.Errorf("Error: TestTypedCacheErrors Got %v, Want %v", err, ErrCodecUnknownTag)
+	}
+	if err := tc.Decode([]byte{1}, &u); err != ErrCodecShortValue {
+		t.Errorf("Error: TestTypedCacheErrors Got %v, Want %v", err, ErrCodecShortValue)
+	}
+}
+
+func TestProtoMessage(t *testing.T) {
+	tc := testTypedCache(t, testMemoryCache(t), NewTypedCacheBuilder().SetCodec(Proto{}))
+
+	ts := &timestamp.Timestamp{Seconds: 1546300800, Nanos: 5}
+	if err := tc.Put(context.Background(), "1", ts, 60); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var got timestamp.Timestamp
+	ok, err := tc.Get(context.Background(), "1", &got)
+	if err != nil || !ok || !proto.Equal(&got, ts) {
+		t.Errorf("Error: TestProtoMessage Got %v %v %v, Want %v", &got, ok, err, ts)
+	}
+}
+
+func TestZstdBuild(t *testing.T) {
+	_, err := NewTypedCacheBuilder().SetCache(testMemoryCache(t)).SetCompression(Zstd, 0).Build()
+	if (zstdEnabled && err != nil) || (!zstdEnabled && err != ErrCodecZstdUnsupported) {
+		t.Errorf("Error: TestZstdBuild Got %v, Want error only without cgo", err)
+	}
+}
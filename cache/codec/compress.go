@@ -0,0 +1,41 @@
+package codec
+
+import (
+	"github.com/golang/snappy"
+)
+
+// Compression : compression of an item, stored next to the value
+type Compression byte
+
+const (
+	// None : value is not compressed
+	None Compression = iota
+	// Snappy :
+	Snappy
+	// Zstd : needs cgo, see ErrCodecZstdUnsupported
+	Zstd
+)
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case None:
+		return data, nil
+	case Snappy:
+		return snappy.Encode(nil, data), nil
+	case Zstd:
+		return zstdCompress(data)
+	}
+	return nil, ErrCodecUnknownTag
+}
+
+func decompress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case None:
+		return data, nil
+	case Snappy:
+		return snappy.Decode(nil, data)
+	case Zstd:
+		return zstdDecompress(data)
+	}
+	return nil, ErrCodecUnknownTag
+}
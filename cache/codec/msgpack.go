@@ -0,0 +1,30 @@
+package codec
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Msgpack : msgpack codec for types generated by github.com/tinylib/msgp
+type Msgpack struct{}
+
+// Tag :
+func (Msgpack) Tag() Tag { return TagMsgpack }
+
+// Marshal : v must implement msgp.Marshaler
+func (Msgpack) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(msgp.Marshaler)
+	if !ok {
+		return nil, ErrCodecUnsupportedType
+	}
+	return m.MarshalMsg(nil)
+}
+
+// Unmarshal : v must implement msgp.Unmarshaler
+func (Msgpack) Unmarshal(data []byte, v interface{}) error {
+	u, ok := v.(msgp.Unmarshaler)
+	if !ok {
+		return ErrCodecUnsupportedType
+	}
+	_, err := u.UnmarshalMsg(data)
+	return err
+}
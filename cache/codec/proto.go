@@ -0,0 +1,41 @@
+package codec
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// protoMessage : protobuf message with generated marshalling methods, as
+// generated by gogo/protobuf
+type protoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal([]byte) error
+}
+
+// Proto : protobuf codec for messages with generated Marshal and Unmarshal
+// methods, or else proto.Message of golang/protobuf
+type Proto struct{}
+
+// Tag :
+func (Proto) Tag() Tag { return TagProto }
+
+// Marshal :
+func (Proto) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case protoMessage:
+		return m.Marshal()
+	case proto.Message:
+		return proto.Marshal(m)
+	}
+	return nil, ErrCodecUnsupportedType
+}
+
+// Unmarshal :
+func (Proto) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case protoMessage:
+		return m.Unmarshal(data)
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	}
+	return ErrCodecUnsupportedType
+}
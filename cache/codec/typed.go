@@ -0,0 +1,140 @@
+package codec
+
+import (
+	"context"
+
+	"github.com/alokic/gopkg/cache"
+)
+
+var (
+	// header of item value: codec tag, compression
+	headerSize = 2
+)
+
+// TypedCacheBuilder :
+type TypedCacheBuilder struct {
+	tc *TypedCache
+}
+
+// TypedCache : puts and gets values serialized by a codec. Items are read
+// with the codec they were written with, so codec and compression can change
+// without breaking existing items
+type TypedCache struct {
+	cache       cache.Cache
+	codec       Codec
+	codecs      map[Tag]Codec
+	compression Compression
+	threshold   int
+}
+
+// NewTypedCacheBuilder : JSON codec, no compression by default
+func NewTypedCacheBuilder() *TypedCacheBuilder {
+	tc := &TypedCache{codec: JSON{}, codecs: make(map[Tag]Codec)}
+	for _, v := range []Codec{JSON{}, Msgpack{}, Proto{}, Gob{}} {
+		tc.codecs[v.Tag()] = v
+	}
+	return &TypedCacheBuilder{tc: tc}
+}
+
+// SetCache : cache to store items in
+func (b *TypedCacheBuilder) SetCache(c cache.Cache) *TypedCacheBuilder {
+	b.tc.cache = c
+	return b
+}
+
+// SetCodec : codec to write items with, it is also used to read items of its tag
+func (b *TypedCacheBuilder) SetCodec(c Codec) *TypedCacheBuilder {
+	b.tc.codec = c
+	b.tc.codecs[c.Tag()] = c
+	return b
+}
+
+// AddCodec : codec to read items of its tag, for custom codecs items were written with
+func (b *TypedCacheBuilder) AddCodec(c Codec) *TypedCacheBuilder {
+	b.tc.codecs[c.Tag()] = c
+	return b
+}
+
+// SetCompression : compress values of at least threshold bytes. Build fails
+// with Zstd when built without cgo
+func (b *TypedCacheBuilder) SetCompression(c Compression, threshold int) *TypedCacheBuilder {
+	b.tc.compression = c
+	b.tc.threshold = threshold
+	return b
+}
+
+// Build :
+func (b *TypedCacheBuilder) Build() (*TypedCache, error) {
+	if b.tc.cache == nil {
+		return nil, cache.ErrCacheNotFound
+	}
+	if b.tc.compression == Zstd && !zstdEnabled {
+		return nil, ErrCodecZstdUnsupported
+	}
+	return b.tc, nil
+}
+
+// Put : put v serialized under key
+func (c *TypedCache) Put(ctx context.Context, key string, v interface{}, ttl int64) error {
+	data, err := c.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.cache.Put(ctx, &cache.Item{Key: key, Val: data, TTL: ttl})
+	return err
+}
+
+// Get : get value of key into v. Returns false when key is not found
+func (c *TypedCache) Get(ctx context.Context, key string, v interface{}) (bool, error) {
+	item, err := c.cache.Get(ctx, key)
+	if err != nil || item == nil {
+		return false, err
+	}
+
+	if err := c.Decode(item.Val, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete : delete a item based on ID
+func (c *TypedCache) Delete(ctx context.Context, key string) error {
+	return c.cache.Delete(ctx, key)
+}
+
+// Encode : item value of v, for use with multi key operations of cache
+func (c *TypedCache) Encode(v interface{}) ([]byte, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	comp := None
+	if c.compression != None && len(data) >= c.threshold {
+		comp = c.compression
+		if data, err = compress(comp, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return append([]byte{byte(c.codec.Tag()), byte(comp)}, data...), nil
+}
+
+// Decode : item value into v, with codec and compression it was written with
+func (c *TypedCache) Decode(data []byte, v interface{}) error {
+	if len(data) < headerSize {
+		return ErrCodecShortValue
+	}
+
+	codec, ok := c.codecs[Tag(data[0])]
+	if !ok {
+		return ErrCodecUnknownTag
+	}
+
+	data, err := decompress(Compression(data[1]), data[headerSize:])
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, v)
+}
@@ -0,0 +1,19 @@
+//go:build cgo
+// +build cgo
+
+package codec
+
+import (
+	"github.com/DataDog/zstd"
+)
+
+// zstd binds the C library
+const zstdEnabled = true
+
+func zstdCompress(data []byte) ([]byte, error) {
+	return zstd.Compress(nil, data)
+}
+
+func zstdDecompress(data []byte) ([]byte, error) {
+	return zstd.Decompress(nil, data)
+}
@@ -0,0 +1,15 @@
+//go:build !cgo
+// +build !cgo
+
+package codec
+
+// zstd binds the C library, it is not built without cgo
+const zstdEnabled = false
+
+func zstdCompress(data []byte) ([]byte, error) {
+	return nil, ErrCodecZstdUnsupported
+}
+
+func zstdDecompress(data []byte) ([]byte, error) {
+	return nil, ErrCodecZstdUnsupported
+}
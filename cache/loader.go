@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrCacheNotFound : cache to load into is not set
+	ErrCacheNotFound = errors.New("Cache: cache not found")
+
+	// negativeVal : value of item cached for a key which loader did not find
+	negativeVal = []byte("\x00cache:negative\x00")
+
+	// load duration assumed before first load is measured
+	defaultLoadDuration = 100 * time.Millisecond
+)
+
+// Loader : loads item of key on a miss. Returns nil item when key does not exist
+type Loader func(ctx context.Context) (*Item, error)
+
+// LoadingCacheBuilder :
+type LoadingCacheBuilder struct {
+	lc *LoadingCache
+}
+
+// LoadingCache : cache which loads missed keys. Concurrent misses of a key
+// share one loader call
+type LoadingCache struct {
+	// average load duration in nanoseconds, first field to be 64-bit aligned for atomic
+	loadDuration int64
+
+	Cache
+
+	beta        float64
+	negativeTTL int64
+
+	mu     sync.Mutex
+	flight map[string]*call
+}
+
+// call : loader call in flight, done is closed with its result
+type call struct {
+	done chan struct{}
+	item *Item
+	err  error
+}
+
+// NewLoadingCacheBuilder :
+func NewLoadingCacheBuilder() *LoadingCacheBuilder {
+	return &LoadingCacheBuilder{lc: &LoadingCache{
+		loadDuration: int64(defaultLoadDuration),
+		flight:       make(map[string]*call),
+	}}
+}
+
+// SetCache : cache to load into
+func (b *LoadingCacheBuilder) SetCache(c Cache) *LoadingCacheBuilder {
+	b.lc.Cache = c
+	return b
+}
+
+// SetEarlyRefresh : refresh item in background before it expires, with
+// probability growing as TTL runs out. Larger beta refreshes earlier, 1 is a
+// good default and 0 disables it
+func (b *LoadingCacheBuilder) SetEarlyRefresh(beta float64) *LoadingCacheBuilder {
+	b.lc.beta = beta
+	return b
+}
+
+// SetNegativeTTL : seconds to remember a key which loader did not find, 0 disables it
+func (b *LoadingCacheBuilder) SetNegativeTTL(seconds int64) *LoadingCacheBuilder {
+	b.lc.negativeTTL = seconds
+	return b
+}
+
+// Build :
+func (b *LoadingCacheBuilder) Build() (*LoadingCache, error) {
+	if b.lc.Cache == nil {
+		return nil, ErrCacheNotFound
+	}
+	return b.lc, nil
+}
+
+// Get : get a item, nil for a key remembered as not found
+func (c *LoadingCache) Get(ctx context.Context, key string) (*Item, error) {
+	item, err := c.Cache.Get(ctx, key)
+	if err != nil || item == nil || isNegative(item) {
+		return nil, err
+	}
+	return item, nil
+}
+
+// MultiGet : Get multiple keys, nil for keys remembered as not found
+func (c *LoadingCache) MultiGet(ctx context.Context, keys []string) ([]*Item, error) {
+	items, err := c.Cache.MultiGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range items {
+		if v != nil && isNegative(v) {
+			items[i] = nil
+		}
+	}
+	return items, nil
+}
+
+// GetOrLoad : get item of key, calling loader and putting its item on a miss.
+// Returns nil item when loader does not find key
+func (c *LoadingCache) GetOrLoad(ctx context.Context, key string, loader Loader) (*Item, error) {
+	item, err := c.Cache.Get(ctx, key)
+	if err != nil || item == nil {
+		return c.load(ctx, key, loader)
+	}
+
+	if c.refreshEarly(item) {
+		go c.refresh(key, loader)
+	}
+
+	if isNegative(item) {
+		return nil, nil
+	}
+	return item, nil
+}
+
+// refresh : load in background, a loader panic is logged since no caller
+// can recover it
+func (c *LoadingCache) refresh(key string, loader Loader) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Error: Loading cache refresh of", key, "panicked: ", r)
+		}
+	}()
+
+	c.load(context.Background(), key, loader)
+}
+
+// refreshEarly : probabilistic early expiration, refresh when
+// loadDuration * beta * -ln(rand) reaches remaining TTL
+func (c *LoadingCache) refreshEarly(item *Item) bool {
+	if c.beta <= 0 || item.TTL <= 0 {
+		return false
+	}
+
+	gap := float64(atomic.LoadInt64(&c.loadDuration)) * c.beta * -math.Log(1-rand.Float64())
+	return gap >= float64(time.Duration(item.TTL)*time.Second)
+}
+
+// load : one loader call per key at a time, others wait for its result till
+// their own ctx is done. A waiter loads again when the call failed only since
+// ctx of its caller is done
+func (c *LoadingCache) load(ctx context.Context, key string, loader Loader) (*Item, error) {
+	c.mu.Lock()
+	if cl, ok := c.flight[key]; ok {
+		c.mu.Unlock()
+
+		select {
+		case <-cl.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if (cl.err == context.Canceled || cl.err == context.DeadlineExceeded) && ctx.Err() == nil {
+			return c.load(ctx, key, loader)
+		}
+		return cl.item, cl.err
+	}
+
+	cl := &call{done: make(chan struct{})}
+	c.flight[key] = cl
+	c.mu.Unlock()
+
+	// waiters are released with an error even if loader panics, panic goes
+	// on to caller of load
+	defer func() {
+		if r := recover(); r != nil {
+			cl.item, cl.err = nil, fmt.Errorf("Cache: loader panicked: %v", r)
+			c.release(key, cl)
+			panic(r)
+		}
+		c.release(key, cl)
+	}()
+
+	cl.item, cl.err = c.loadAndPut(ctx, key, loader)
+	return cl.item, cl.err
+}
+
+// release : remove call from flight and wake its waiters
+func (c *LoadingCache) release(key string, cl *call) {
+	c.mu.Lock()
+	delete(c.flight, key)
+	c.mu.Unlock()
+	close(cl.done)
+}
+
+func (c *LoadingCache) loadAndPut(ctx context.Context, key string, loader Loader) (*Item, error) {
+	start := time.Now()
+	item, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.observeLoad(time.Since(start))
+
+	if item == nil {
+		if c.negativeTTL > 0 {
+			c.Put(ctx, &Item{Key: key, Val: negativeVal, TTL: c.negativeTTL})
+		}
+		return nil, nil
+	}
+
+	if item.Key == "" {
+		item.Key = key
+	}
+
+	// loaded item is returned even if it could not be cached
+	c.Put(ctx, item)
+	return item, nil
+}
+
+func isNegative(item *Item) bool {
+	return bytes.Equal(item.Val, negativeVal)
+}
+
+// observeLoad : moving average of load duration
+func (c *LoadingCache) observeLoad(d time.Duration) {
+	old := atomic.LoadInt64(&c.loadDuration)
+	atomic.StoreInt64(&c.loadDuration, old-old/8+int64(d)/8)
+}
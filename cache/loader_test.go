@@ -0,0 +1,234 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alokic/gopkg/cache"
+	"github.com/alokic/gopkg/cache/memory"
+)
+
+func testLoadingCache(t *testing.T, b *cache.LoadingCacheBuilder) *cache.LoadingCache {
+	m, err := memory.NewCacheBuilder().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := b.SetCache(m).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestGetOrLoadCoalesce(t *testing.T) {
+	c := testLoadingCache(t, cache.NewLoadingCacheBuilder())
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (*cache.Item, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &cache.Item{Val: []byte("v"), TTL: 60}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			item, err := c.GetOrLoad(context.Background(), "k", loader)
+			if err != nil || item == nil || item.Key != "k" || string(item.Val) != "v" {
+				t.Errorf("Error: TestGetOrLoadCoalesce Got %v %v, Want loaded item", item, err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Error: TestGetOrLoadCoalesce Got %d loader calls, Want %d", calls, 1)
+	}
+
+	// cached now
+	c.GetOrLoad(context.Background(), "k", loader)
+	if calls != 1 {
+		t.Errorf("Error: TestGetOrLoadCoalesce Got %d loader calls, Want %d", calls, 1)
+	}
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	c := testLoadingCache(t, cache.NewLoadingCacheBuilder())
+	errLoad := errors.New("load failed")
+
+	_, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (*cache.Item, error) {
+		return nil, errLoad
+	})
+	if err != errLoad {
+		t.Errorf("Error: TestGetOrLoadError Got %v, Want %v", err, errLoad)
+	}
+
+	if item, _ := c.Get(context.Background(), "k"); item != nil {
+		t.Errorf("Error: TestGetOrLoadError Got %v, Want nil", item)
+	}
+}
+
+func TestGetOrLoadNegative(t *testing.T) {
+	c := testLoadingCache(t, cache.NewLoadingCacheBuilder().SetNegativeTTL(60))
+
+	var calls int32
+	loader := func(ctx context.Context) (*cache.Item, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		item, err := c.GetOrLoad(context.Background(), "k", loader)
+		if err != nil || item != nil {
+			t.Errorf("Error: TestGetOrLoadNegative Got %v %v, Want nil", item, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Error: TestGetOrLoadNegative Got %d loader calls, Want %d", calls, 1)
+	}
+
+	if item, _ := c.Get(context.Background(), "k"); item != nil {
+		t.Errorf("Error: TestGetOrLoadNegative Got %v, Want nil", item)
+	}
+}
+
+func TestGetOrLoadEarlyRefresh(t *testing.T) {
+	// beta is large enough to always refresh
+	c := testLoadingCache(t, cache.NewLoadingCacheBuilder().SetEarlyRefresh(1e6))
+	c.Put(context.Background(), &cache.Item{Key: "k", Val: []byte("old"), TTL: 60})
+
+	refreshed := make(chan struct{})
+	item, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (*cache.Item, error) {
+		defer close(refreshed)
+		return &cache.Item{Val: []byte("new"), TTL: 60}, nil
+	})
+	if err != nil || string(item.Val) != "old" {
+		t.Errorf("Error: TestGetOrLoadEarlyRefresh Got %v %v, Want current item", item, err)
+		return
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Errorf("Error: TestGetOrLoadEarlyRefresh item not refreshed")
+		return
+	}
+
+	// put follows loader return
+	time.Sleep(10 * time.Millisecond)
+	if item, _ := c.Get(context.Background(), "k"); item == nil || string(item.Val) != "new" {
+		t.Errorf("Error: TestGetOrLoadEarlyRefresh Got %v, Want %s", item, "new")
+	}
+}
+
+func TestGetOrLoadWaiterContext(t *testing.T) {
+	c := testLoadingCache(t, cache.NewLoadingCacheBuilder())
+
+	release := make(chan struct{})
+	defer close(release)
+	go c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (*cache.Item, error) {
+		<-release
+		return &cache.Item{Val: []byte("v"), TTL: 60}, nil
+	})
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetOrLoad(ctx, "k", nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Error: TestGetOrLoadWaiterContext Got %v, Want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestGetOrLoadCancelledCaller(t *testing.T) {
+	c := testLoadingCache(t, cache.NewLoadingCacheBuilder())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	loader := func(ctx context.Context) (*cache.Item, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	go c.GetOrLoad(ctx, "k", loader)
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	var item *cache.Item
+	var err error
+	go func() {
+		defer close(done)
+		item, err = c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (*cache.Item, error) {
+			return &cache.Item{Val: []byte("v"), TTL: 60}, nil
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	// waiter loads again instead of failing with context.Canceled of caller
+	if err != nil || item == nil || string(item.Val) != "v" {
+		t.Errorf("Error: TestGetOrLoadCancelledCaller Got %v %v, Want loaded item", item, err)
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	c := testLoadingCache(t, cache.NewLoadingCacheBuilder())
+
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (*cache.Item, error) {
+			<-release
+			panic("load failed")
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(context.Background(), "k", nil)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	if err := <-done; err == nil {
+		t.Errorf("Error: TestGetOrLoadPanic Got %v, Want error", err)
+	}
+}
+
+func TestGetOrLoadRefreshPanic(t *testing.T) {
+	// beta is large enough to always refresh
+	c := testLoadingCache(t, cache.NewLoadingCacheBuilder().SetEarlyRefresh(1e6))
+	c.Put(context.Background(), &cache.Item{Key: "k", Val: []byte("old"), TTL: 60})
+
+	refreshed := make(chan struct{})
+	item, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (*cache.Item, error) {
+		close(refreshed)
+		panic("load failed")
+	})
+	if err != nil || string(item.Val) != "old" {
+		t.Errorf("Error: TestGetOrLoadRefreshPanic Got %v %v, Want current item", item, err)
+	}
+
+	// process is still alive and key can be loaded again
+	<-refreshed
+	time.Sleep(10 * time.Millisecond)
+	item, err = c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (*cache.Item, error) {
+		return &cache.Item{Val: []byte("new"), TTL: 60}, nil
+	})
+	if err != nil || item == nil {
+		t.Errorf("Error: TestGetOrLoadRefreshPanic Got %v %v, Want item", item, err)
+	}
+}
@@ -0,0 +1,312 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alokic/gopkg/cache"
+)
+
+var (
+	// ErrCacheInvalidShards : shard count is not positive
+	ErrCacheInvalidShards = errors.New("Cache: shards should be positive")
+
+	// ErrCacheItemTooLarge : item does not fit in byte budget of cache
+	ErrCacheItemTooLarge = errors.New("Cache: item is larger than byte budget")
+
+	defaultShards = 16
+
+	// expired items probed on every put, like active expiry of redis
+	expireProbes = 2
+
+	// only for test simulation
+	now = time.Now
+)
+
+// CacheBuilder :
+type CacheBuilder struct {
+	mc *memoryCache
+}
+
+// memoryCache : keys are spread on shards, each with own lock and eviction
+// order, so that concurrent callers rarely contend. Limits are of whole cache
+type memoryCache struct {
+	usage      usage
+	shards     []*shard
+	numShards  int
+	maxEntries int64
+	maxBytes   int64
+	policy     Policy
+}
+
+// usage : items and bytes of all shards, updated atomically under lock of
+// shard which changed
+type usage struct {
+	entries int64
+	bytes   int64
+}
+
+// shard :
+type shard struct {
+	mu    sync.Mutex
+	items map[string]*entry
+	evict evictor
+	usage *usage
+}
+
+// entry :
+type entry struct {
+	key      string
+	val      []byte
+	expireAt time.Time // zero when item never expires
+
+	// eviction bookkeeping
+	elem  *list.Element
+	freq  uint64
+	tick  uint64
+	index int
+}
+
+// NewCacheBuilder :
+func NewCacheBuilder() *CacheBuilder {
+	return &CacheBuilder{mc: &memoryCache{numShards: defaultShards}}
+}
+
+// SetMaxEntries : maximum number of items, 0 is unbounded
+func (c *CacheBuilder) SetMaxEntries(n int) *CacheBuilder {
+	c.mc.maxEntries = int64(n)
+	return c
+}
+
+// SetMaxBytes : maximum size of keys and values, 0 is unbounded
+func (c *CacheBuilder) SetMaxBytes(n int64) *CacheBuilder {
+	c.mc.maxBytes = n
+	return c
+}
+
+// SetEvictionPolicy : LRU (default) or LFU
+func (c *CacheBuilder) SetEvictionPolicy(p Policy) *CacheBuilder {
+	c.mc.policy = p
+	return c
+}
+
+// SetShards : number of independently locked shards, default 16.
+// Limits hold for whole cache, a put evicts from its own shard first and from
+// the others only when its shard has nothing left to evict
+func (c *CacheBuilder) SetShards(n int) *CacheBuilder {
+	c.mc.numShards = n
+	return c
+}
+
+// Build :
+func (c *CacheBuilder) Build() (cache.Cache, error) {
+	return c.mc.build()
+}
+
+func (c *memoryCache) build() (cache.Cache, error) {
+	if c.numShards <= 0 {
+		return nil, ErrCacheInvalidShards
+	}
+
+	c.shards = make([]*shard, c.numShards)
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			items: make(map[string]*entry),
+			evict: newEvictor(c.policy),
+			usage: &c.usage,
+		}
+	}
+
+	return c, nil
+}
+
+// Put : put a item, TTL of 0 or less never expires
+func (c *memoryCache) Put(ctx context.Context, r *cache.Item) (interface{}, error) {
+	// copy, so that caller can not change cached value like with redis
+	e := &entry{key: r.Key, val: append([]byte(nil), r.Val...)}
+	if r.TTL > 0 {
+		e.expireAt = now().Add(time.Duration(r.TTL) * time.Second)
+	}
+
+	return nil, c.put(e)
+}
+
+// Get : get a item
+func (c *memoryCache) Get(ctx context.Context, key string) (*cache.Item, error) {
+	return c.shard(key).get(key), nil
+}
+
+// Delete : delete a item based on ID
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.shard(key).delete(key)
+	return nil
+}
+
+// MultiGet : Get multiple keys, aligned with keys
+func (c *memoryCache) MultiGet(ctx context.Context, keys []string) ([]*cache.Item, error) {
+	items := make([]*cache.Item, len(keys))
+	for i, k := range keys {
+		items[i] = c.shard(k).get(k)
+	}
+
+	return items, nil
+}
+
+// MultiPut : put multiple items
+func (c *memoryCache) MultiPut(ctx context.Context, items []*cache.Item) error {
+	for _, v := range items {
+		if _, err := c.Put(ctx, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MultiDelete : Delete multiple keys
+func (c *memoryCache) MultiDelete(ctx context.Context, keys []string) error {
+	for _, k := range keys {
+		c.shard(k).delete(k)
+	}
+
+	return nil
+}
+
+func (c *memoryCache) shard(key string) *shard {
+	return c.shards[c.shardIndex(key)]
+}
+
+func (c *memoryCache) shardIndex(key string) int {
+	if len(c.shards) == 1 {
+		return 0
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(c.shards)))
+}
+
+func (c *memoryCache) put(e *entry) error {
+	size := e.size()
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return ErrCacheItemTooLarge
+	}
+
+	idx := c.shardIndex(e.key)
+	s := c.shards[idx]
+
+	s.mu.Lock()
+	if old, ok := s.items[e.key]; ok {
+		s.remove(old)
+	}
+	s.expire(now())
+
+	for len(s.items) > 0 && c.full(1, size) {
+		s.remove(s.evict.victim())
+	}
+	s.add(e)
+	s.mu.Unlock()
+
+	// own shard had too little to evict, or concurrent puts filled cache:
+	// evict from the others, own shard last, holding one lock at a time
+	for i := 1; i <= len(c.shards) && c.full(0, 0); i++ {
+		o := c.shards[(idx+i)%len(c.shards)]
+
+		o.mu.Lock()
+		for len(o.items) > 0 && c.full(0, 0) {
+			o.remove(o.evict.victim())
+		}
+		o.mu.Unlock()
+	}
+
+	return nil
+}
+
+// full : whether adding n items of size bytes would exceed limits
+func (c *memoryCache) full(n, size int64) bool {
+	if c.maxEntries > 0 && atomic.LoadInt64(&c.usage.entries)+n > c.maxEntries {
+		return true
+	}
+	return c.maxBytes > 0 && atomic.LoadInt64(&c.usage.bytes)+size > c.maxBytes
+}
+
+func (s *shard) get(key string) *cache.Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+
+	t := now()
+	if e.expired(t) {
+		s.remove(e)
+		return nil
+	}
+
+	s.evict.access(e)
+	return &cache.Item{Key: e.key, Val: append([]byte(nil), e.val...), TTL: e.ttl(t)}
+}
+
+func (s *shard) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		s.remove(e)
+	}
+}
+
+// expire : drop expired items among a few random ones, so that items which
+// are never read again do not stay till eviction
+func (s *shard) expire(t time.Time) {
+	n := 0
+	for _, e := range s.items {
+		if e.expired(t) {
+			s.remove(e)
+		}
+		if n++; n == expireProbes {
+			return
+		}
+	}
+}
+
+func (s *shard) add(e *entry) {
+	s.items[e.key] = e
+	s.evict.add(e)
+	atomic.AddInt64(&s.usage.entries, 1)
+	atomic.AddInt64(&s.usage.bytes, e.size())
+}
+
+func (s *shard) remove(e *entry) {
+	delete(s.items, e.key)
+	s.evict.remove(e)
+	atomic.AddInt64(&s.usage.entries, -1)
+	atomic.AddInt64(&s.usage.bytes, -e.size())
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.val))
+}
+
+func (e *entry) expired(t time.Time) bool {
+	return !e.expireAt.IsZero() && !t.Before(e.expireAt)
+}
+
+// ttl : remaining seconds rounded up, -1 when item never expires like redis TTL
+func (e *entry) ttl(t time.Time) int64 {
+	if e.expireAt.IsZero() {
+		return -1
+	}
+	return ceilDiv(int64(e.expireAt.Sub(t)), int64(time.Second))
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
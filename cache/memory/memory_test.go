@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alokic/gopkg/cache"
+	"github.com/alokic/gopkg/cache/cachetest"
+)
+
+func testCacheCreate(b *CacheBuilder) cache.Cache {
+	c, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func testPut(c cache.Cache, key, val string, ttl int64) {
+	c.Put(context.Background(), &cache.Item{Key: key, Val: []byte(val), TTL: ttl})
+}
+
+func testExists(c cache.Cache, key string) bool {
+	r, _ := c.Get(context.Background(), key)
+	return r != nil
+}
+
+func TestCacheSuite(t *testing.T) {
+	for _, p := range []Policy{LRU, LFU} {
+		cachetest.Run(t, func() cache.Cache {
+			return testCacheCreate(NewCacheBuilder().SetMaxEntries(1000).SetEvictionPolicy(p))
+		})
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	clock := time.Now()
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	c := testCacheCreate(NewCacheBuilder())
+	testPut(c, "1", "1", 10)
+	testPut(c, "2", "2", 0)
+
+	clock = clock.Add(4 * time.Second)
+	if r, _ := c.Get(context.Background(), "1"); r == nil || r.TTL != 6 {
+		t.Errorf("Error: TestCacheTTL Got %v, Want TTL %d", r, 6)
+	}
+
+	clock = clock.Add(6 * time.Second)
+	if testExists(c, "1") {
+		t.Errorf("Error: TestCacheTTL Got item, Want expired")
+	}
+	if r, _ := c.Get(context.Background(), "2"); r == nil || r.TTL != -1 {
+		t.Errorf("Error: TestCacheTTL Got %v, Want never expiring item", r)
+	}
+}
+
+func TestCacheLRU(t *testing.T) {
+	c := testCacheCreate(NewCacheBuilder().SetShards(1).SetMaxEntries(2))
+	testPut(c, "1", "1", 60)
+	testPut(c, "2", "2", 60)
+	testExists(c, "1")
+	testPut(c, "3", "3", 60)
+
+	if !testExists(c, "1") || testExists(c, "2") || !testExists(c, "3") {
+		t.Errorf("Error: TestCacheLRU Got %v %v %v, Want true false true", testExists(c, "1"), testExists(c, "2"), testExists(c, "3"))
+	}
+}
+
+func TestCacheLFU(t *testing.T) {
+	c := testCacheCreate(NewCacheBuilder().SetShards(1).SetMaxEntries(2).SetEvictionPolicy(LFU))
+	testPut(c, "1", "1", 60)
+	testPut(c, "2", "2", 60)
+	testExists(c, "1")
+	testExists(c, "1")
+	testExists(c, "2")
+	testPut(c, "3", "3", 60)
+
+	if !testExists(c, "1") || testExists(c, "2") {
+		t.Errorf("Error: TestCacheLFU Got %v %v, Want true false", testExists(c, "1"), testExists(c, "2"))
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	c := testCacheCreate(NewCacheBuilder().SetShards(1).SetMaxBytes(10))
+	testPut(c, "1", "1234", 60)
+	testPut(c, "2", "1234", 60)
+	testPut(c, "3", "1234", 60)
+
+	if testExists(c, "1") || !testExists(c, "2") || !testExists(c, "3") {
+		t.Errorf("Error: TestCacheMaxBytes Got %v %v %v, Want false true true", testExists(c, "1"), testExists(c, "2"), testExists(c, "3"))
+	}
+
+	_, err := c.Put(context.Background(), &cache.Item{Key: "4", Val: make([]byte, 10)})
+	if err != ErrCacheItemTooLarge {
+		t.Errorf("Error: TestCacheMaxBytes Got %v, Want %v", err, ErrCacheItemTooLarge)
+	}
+}
+
+func TestCacheLimitsShards(t *testing.T) {
+	c := testCacheCreate(NewCacheBuilder().SetMaxEntries(10).SetMaxBytes(100))
+	for i := 0; i < 100; i++ {
+		testPut(c, fmt.Sprintf("%02d", i), "12345678", 60)
+	}
+
+	n, size := 0, int64(0)
+	for _, s := range c.(*memoryCache).shards {
+		for _, e := range s.items {
+			n++
+			size += e.size()
+		}
+	}
+	if n != 10 || size != 100 {
+		t.Errorf("Error: TestCacheLimitsShards Got %d items of %d bytes, Want %d items of %d bytes", n, size, 10, 100)
+	}
+}
+
+func TestCacheMaxBytesShards(t *testing.T) {
+	c := testCacheCreate(NewCacheBuilder().SetMaxBytes(10))
+	testPut(c, "1", "12345678", 60)
+
+	if !testExists(c, "1") {
+		t.Errorf("Error: TestCacheMaxBytesShards Got %v, Want %v", false, true)
+	}
+
+	_, err := c.Put(context.Background(), &cache.Item{Key: "2", Val: make([]byte, 10)})
+	if err != ErrCacheItemTooLarge {
+		t.Errorf("Error: TestCacheMaxBytesShards Got %v, Want %v", err, ErrCacheItemTooLarge)
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	for _, p := range []Policy{LRU, LFU} {
+		c := testCacheCreate(NewCacheBuilder().SetMaxEntries(100).SetEvictionPolicy(p))
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					k := fmt.Sprintf("%d", (g*i)%300)
+					testPut(c, k, k, 60)
+					c.Get(context.Background(), k)
+					if i%10 == 0 {
+						c.Delete(context.Background(), k)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		n := 0
+		for _, s := range c.(*memoryCache).shards {
+			n += len(s.items)
+		}
+		if n > 100 {
+			t.Errorf("Error: TestCacheConcurrent Got %d items, Want at most %d", n, 100)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// Policy : eviction policy
+type Policy int
+
+const (
+	// LRU : evict least recently used entry
+	LRU Policy = iota
+
+	// LFU : evict least frequently used entry, least recently used among equals
+	LFU
+)
+
+// evictor : tracks entries of a shard to pick eviction victim
+type evictor interface {
+	add(e *entry)
+	access(e *entry)
+	remove(e *entry)
+	victim() *entry
+}
+
+func newEvictor(p Policy) evictor {
+	if p == LFU {
+		return &lfu{}
+	}
+	return &lru{l: list.New()}
+}
+
+// lru : most recently used entry at front
+type lru struct {
+	l *list.List
+}
+
+func (p *lru) add(e *entry) {
+	e.elem = p.l.PushFront(e)
+}
+
+func (p *lru) access(e *entry) {
+	p.l.MoveToFront(e.elem)
+}
+
+func (p *lru) remove(e *entry) {
+	p.l.Remove(e.elem)
+}
+
+func (p *lru) victim() *entry {
+	if b := p.l.Back(); b != nil {
+		return b.Value.(*entry)
+	}
+	return nil
+}
+
+// lfu : min heap on (frequency, last access)
+type lfu struct {
+	h    lfuHeap
+	tick uint64
+}
+
+func (p *lfu) add(e *entry) {
+	p.tick++
+	e.freq, e.tick = 1, p.tick
+	heap.Push(&p.h, e)
+}
+
+func (p *lfu) access(e *entry) {
+	p.tick++
+	e.freq, e.tick = e.freq+1, p.tick
+	heap.Fix(&p.h, e.index)
+}
+
+func (p *lfu) remove(e *entry) {
+	heap.Remove(&p.h, e.index)
+}
+
+func (p *lfu) victim() *entry {
+	if len(p.h) == 0 {
+		return nil
+	}
+	return p.h[0]
+}
+
+type lfuHeap []*entry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,395 @@
+package near
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/alokic/gopkg/cache"
+	"github.com/alokic/gopkg/cache/memory"
+	farm "github.com/alokic/gopkg/redisfarm"
+)
+
+var (
+	// ErrCacheRemoteNotFound : remote tier is not set
+	ErrCacheRemoteNotFound = errors.New("Cache: remote cache not found")
+
+	// ErrCacheFarmNotFound : farm to broadcast invalidations is not set
+	ErrCacheFarmNotFound = errors.New("Cache: farm not found")
+
+	// ErrCacheRemoteNotInvalidator : remote tier can not invalidate by tag or prefix
+	ErrCacheRemoteNotInvalidator = errors.New("Cache: remote cache is not an invalidator")
+
+	defaultLocalTTL   = int64(5)
+	defaultMaxEntries = 10000
+	channelPrefix     = "near:invalidate"
+
+	// local value is prefixed with remote expiry and flush epoch
+	localHeaderLen = 16
+
+	// only for test simulation
+	now = time.Now
+)
+
+// CacheBuilder :
+type CacheBuilder struct {
+	nc *nearCache
+}
+
+// nearCache : in-process tier over a remote cache. Writes go to remote first
+// and are broadcast on redis pub/sub, so peers drop their stale local copy.
+// A lost invalidation is bounded by localTTL
+type nearCache struct {
+	// incremented on every invalidation received, a remote read racing with
+	// an invalidation is dropped from local tier after it is put. First field
+	// to be 64-bit aligned for atomic
+	gen uint64
+
+	// incremented on every flush, local items of an older epoch are missed
+	epoch uint64
+
+	local    cache.Cache
+	remote   cache.Cache
+	farm     *farm.Farm
+	ctx      context.Context
+	cancel   context.CancelFunc
+	app      string
+	prefix   string
+	channel  string
+	id       string
+	localTTL int64
+}
+
+// invalidation : message broadcast to peers, Flush drops whole local tier
+type invalidation struct {
+	ID    string   `json:"id"`
+	Keys  []string `json:"keys"`
+	Flush bool     `json:"flush,omitempty"`
+}
+
+// NewCacheBuilder :
+func NewCacheBuilder() *CacheBuilder {
+	return &CacheBuilder{nc: &nearCache{localTTL: defaultLocalTTL, ctx: context.Background()}}
+}
+
+// SetLocal : in-process tier, default is LRU memory cache of 10000 entries
+func (c *CacheBuilder) SetLocal(l cache.Cache) *CacheBuilder {
+	c.nc.local = l
+	return c
+}
+
+// SetRemote : shared tier, usually redis cache
+func (c *CacheBuilder) SetRemote(r cache.Cache) *CacheBuilder {
+	c.nc.remote = r
+	return c
+}
+
+// SetFarm : farm to broadcast invalidations on
+func (c *CacheBuilder) SetFarm(f *farm.Farm) *CacheBuilder {
+	c.nc.farm = f
+	return c
+}
+
+// SetApp : app and prefix name the invalidation channel, use same as remote cache
+func (c *CacheBuilder) SetApp(name string) *CacheBuilder {
+	c.nc.app = name
+	return c
+}
+
+// SetPrefix :
+func (c *CacheBuilder) SetPrefix(name string) *CacheBuilder {
+	c.nc.prefix = name
+	return c
+}
+
+// SetLocalTTL : maximum seconds an item lives in local tier, default 5
+func (c *CacheBuilder) SetLocalTTL(seconds int64) *CacheBuilder {
+	c.nc.localTTL = seconds
+	return c
+}
+
+// SetContext : invalidations are received till ctx is cancelled or cache is closed
+func (c *CacheBuilder) SetContext(ctx context.Context) *CacheBuilder {
+	c.nc.ctx = ctx
+	return c
+}
+
+// Build : cache is also cache.Invalidator when remote is, and io.Closer to
+// stop receiving invalidations
+func (c *CacheBuilder) Build() (cache.Cache, error) {
+	return c.nc.build()
+}
+
+func (c *nearCache) build() (cache.Cache, error) {
+	if c.remote == nil {
+		return nil, ErrCacheRemoteNotFound
+	}
+	if c.farm == nil {
+		return nil, ErrCacheFarmNotFound
+	}
+
+	if c.local == nil {
+		l, err := memory.NewCacheBuilder().SetMaxEntries(defaultMaxEntries).Build()
+		if err != nil {
+			return nil, err
+		}
+		c.local = l
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	c.id = hex.EncodeToString(id)
+	c.channel = channelPrefix + ":" + c.app + ":" + c.prefix
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.cancel = cancel
+	go c.listen(c.farm.Subscribe(ctx, c.channel))
+
+	return c, nil
+}
+
+// Put : write through remote, then local
+func (c *nearCache) Put(ctx context.Context, r *cache.Item) (interface{}, error) {
+	reply, err := c.remote.Put(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	c.putLocal(ctx, r)
+	c.publish(r.Key)
+
+	return reply, nil
+}
+
+// Get : local, read through remote on miss
+func (c *nearCache) Get(ctx context.Context, key string) (*cache.Item, error) {
+	if item, err := c.local.Get(ctx, key); err == nil {
+		if item = c.fromLocal(item); item != nil {
+			return item, nil
+		}
+	}
+
+	gen := atomic.LoadUint64(&c.gen)
+
+	item, err := c.remote.Get(ctx, key)
+	if err != nil || item == nil {
+		return item, err
+	}
+
+	c.putLocal(ctx, item)
+	if atomic.LoadUint64(&c.gen) != gen {
+		c.local.Delete(ctx, key)
+	}
+	return item, nil
+}
+
+// Delete : delete from remote and local
+func (c *nearCache) Delete(ctx context.Context, key string) error {
+	if err := c.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	c.local.Delete(ctx, key)
+	c.publish(key)
+
+	return nil
+}
+
+// MultiGet : local, read through remote for missed keys
+func (c *nearCache) MultiGet(ctx context.Context, keys []string) ([]*cache.Item, error) {
+	items, err := c.local.MultiGet(ctx, keys)
+	if err != nil {
+		items = make([]*cache.Item, len(keys))
+	}
+	for i, v := range items {
+		items[i] = c.fromLocal(v)
+	}
+
+	var missed []string
+	var pos []int
+	for i, v := range items {
+		if v == nil {
+			missed = append(missed, keys[i])
+			pos = append(pos, i)
+		}
+	}
+	if len(missed) == 0 {
+		return items, nil
+	}
+
+	gen := atomic.LoadUint64(&c.gen)
+
+	remote, err := c.remote.MultiGet(ctx, missed)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range remote {
+		if v == nil {
+			continue
+		}
+		c.putLocal(ctx, v)
+		items[pos[i]] = v
+	}
+	if atomic.LoadUint64(&c.gen) != gen {
+		c.local.MultiDelete(ctx, missed)
+	}
+
+	return items, nil
+}
+
+// MultiPut : write through remote, then local
+func (c *nearCache) MultiPut(ctx context.Context, items []*cache.Item) error {
+	if err := c.remote.MultiPut(ctx, items); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(items))
+	for _, v := range items {
+		c.putLocal(ctx, v)
+		keys = append(keys, v.Key)
+	}
+	c.publish(keys...)
+
+	return nil
+}
+
+// MultiDelete : delete from remote and local
+func (c *nearCache) MultiDelete(ctx context.Context, keys []string) error {
+	if err := c.remote.MultiDelete(ctx, keys); err != nil {
+		return err
+	}
+
+	c.local.MultiDelete(ctx, keys)
+	c.publish(keys...)
+
+	return nil
+}
+
+// InvalidateTag : invalidate on remote, then flush local tier of every peer,
+// as local tier does not know tags
+func (c *nearCache) InvalidateTag(ctx context.Context, tag string) error {
+	inv, ok := c.remote.(cache.Invalidator)
+	if !ok {
+		return ErrCacheRemoteNotInvalidator
+	}
+
+	if err := inv.InvalidateTag(ctx, tag); err != nil {
+		return err
+	}
+
+	c.flush()
+	return nil
+}
+
+// InvalidatePrefix : invalidate on remote, then flush local tier of every peer
+func (c *nearCache) InvalidatePrefix(ctx context.Context, prefix string) error {
+	inv, ok := c.remote.(cache.Invalidator)
+	if !ok {
+		return ErrCacheRemoteNotInvalidator
+	}
+
+	if err := inv.InvalidatePrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	c.flush()
+	return nil
+}
+
+// Close : stop receiving invalidations
+func (c *nearCache) Close() error {
+	c.cancel()
+	return nil
+}
+
+// putLocal : local copy lives at most localTTL, it keeps remote expiry so
+// that a local hit reports remote TTL
+func (c *nearCache) putLocal(ctx context.Context, r *cache.Item) {
+	ttl := c.localTTL
+	if r.TTL > 0 && r.TTL < ttl {
+		ttl = r.TTL
+	}
+
+	var expireAt int64 // zero when item never expires
+	if r.TTL > 0 {
+		expireAt = now().Add(time.Duration(r.TTL) * time.Second).UnixNano()
+	}
+
+	val := make([]byte, localHeaderLen+len(r.Val))
+	binary.BigEndian.PutUint64(val, uint64(expireAt))
+	binary.BigEndian.PutUint64(val[8:], atomic.LoadUint64(&c.epoch))
+	copy(val[localHeaderLen:], r.Val)
+
+	c.local.Put(ctx, &cache.Item{Key: r.Key, Val: val, TTL: ttl})
+}
+
+// fromLocal : item put by putLocal, nil when it is flushed
+func (c *nearCache) fromLocal(item *cache.Item) *cache.Item {
+	if item == nil || len(item.Val) < localHeaderLen {
+		return nil
+	}
+	if binary.BigEndian.Uint64(item.Val[8:]) != atomic.LoadUint64(&c.epoch) {
+		return nil
+	}
+
+	ttl := int64(-1)
+	if expireAt := int64(binary.BigEndian.Uint64(item.Val)); expireAt != 0 {
+		ttl = (expireAt - now().UnixNano() + int64(time.Second) - 1) / int64(time.Second)
+		if ttl <= 0 {
+			return nil
+		}
+	}
+
+	return &cache.Item{Key: item.Key, Val: item.Val[localHeaderLen:], TTL: ttl}
+}
+
+// flush : drop local tier of self and peers
+func (c *nearCache) flush() {
+	atomic.AddUint64(&c.gen, 1)
+	atomic.AddUint64(&c.epoch, 1)
+
+	c.send(invalidation{ID: c.id, Flush: true})
+}
+
+// publish : broadcast invalidation of keys to peers
+func (c *nearCache) publish(keys ...string) {
+	c.send(invalidation{ID: c.id, Keys: keys})
+}
+
+// send : broadcast inv to peers
+func (c *nearCache) send(inv invalidation) {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return
+	}
+
+	if err := c.farm.Publish(c.channel, data); err != nil {
+		log.Println("Error: Near cache invalidation not published: ", err.Error())
+	}
+}
+
+// listen : drop keys invalidated by peers from local tier
+func (c *nearCache) listen(ch <-chan farm.Message) {
+	for m := range ch {
+		var inv invalidation
+		if err := json.Unmarshal(m.Data, &inv); err != nil || inv.ID == c.id {
+			continue
+		}
+
+		atomic.AddUint64(&c.gen, 1)
+		if inv.Flush {
+			atomic.AddUint64(&c.epoch, 1)
+			continue
+		}
+		c.local.MultiDelete(context.Background(), inv.Keys)
+	}
+}
@@ -0,0 +1,223 @@
+package near
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alokic/gopkg/cache"
+	"github.com/alokic/gopkg/cache/cachetest"
+	"github.com/alokic/gopkg/cache/redis"
+	farm "github.com/alokic/gopkg/redisfarm"
+)
+
+var (
+	testServer = "redis://localhost:6379"
+)
+
+func testFarm(t *testing.T) *farm.Farm {
+	cl, err := farm.NewClusterBuilder().SetServers([]string{testServer}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := farm.NewBuilder().SetCluster([]*farm.Cluster{cl}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func testNearCache(t *testing.T, ctx context.Context, f *farm.Farm) cache.Cache {
+	remote, err := redis.NewCacheBuilder().SetFarm(f).SetApp("test").SetPrefix("near").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewCacheBuilder().
+		SetRemote(remote).
+		SetFarm(f).
+		SetApp("test").
+		SetPrefix("near").
+		SetContext(ctx).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCacheSuite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := testFarm(t)
+	cachetest.Run(t, func() cache.Cache {
+		f.AllConn().Do("FLUSHALL")
+		return testNearCache(t, ctx, f)
+	})
+}
+
+func TestCacheReadThrough(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := testFarm(t)
+	f.AllConn().Do("FLUSHALL")
+	c := testNearCache(t, ctx, f)
+
+	c.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("1"), TTL: 60})
+
+	// served from local tier once remote is gone
+	f.AllConn().Do("FLUSHALL")
+	r, err := c.Get(context.Background(), "1")
+	if err != nil || r == nil || string(r.Val) != "1" {
+		t.Errorf("Error: TestCacheReadThrough Got %v %v, Want local item", r, err)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := testFarm(t)
+	f.AllConn().Do("FLUSHALL")
+	a := testNearCache(t, ctx, f)
+	b := testNearCache(t, ctx, f)
+
+	a.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("old"), TTL: 60})
+
+	// b caches old value locally
+	if r, _ := b.Get(context.Background(), "1"); r == nil || string(r.Val) != "old" {
+		t.Errorf("Error: TestCacheInvalidation Got %v, Want %s", r, "old")
+		return
+	}
+
+	// subscription is async, write till b sees it
+	timeout := time.After(2 * time.Second)
+	for {
+		time.Sleep(20 * time.Millisecond)
+		a.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("new"), TTL: 60})
+
+		if r, _ := b.Get(context.Background(), "1"); r != nil && string(r.Val) == "new" {
+			return
+		}
+
+		select {
+		case <-timeout:
+			t.Errorf("Error: TestCacheInvalidation local tier of peer not invalidated")
+			return
+		default:
+		}
+	}
+}
+
+func TestCacheRemoteTTL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := testFarm(t)
+	f.AllConn().Do("FLUSHALL")
+	c := testNearCache(t, ctx, f)
+
+	c.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("1"), TTL: 60})
+
+	// local tier keeps item for 5s only, but reports TTL of remote
+	f.AllConn().Do("FLUSHALL")
+	r, err := c.Get(context.Background(), "1")
+	if err != nil || r == nil || r.TTL <= defaultLocalTTL || r.TTL > 60 {
+		t.Errorf("Error: TestCacheRemoteTTL Got %v %v, Want TTL in (%d, 60]", r, err, defaultLocalTTL)
+	}
+}
+
+func TestCacheInvalidateTag(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := testFarm(t)
+	f.AllConn().Do("FLUSHALL")
+	a := testNearCache(t, ctx, f)
+	b := testNearCache(t, ctx, f)
+
+	inv, ok := a.(cache.Invalidator)
+	if !ok {
+		t.Errorf("Error: TestCacheInvalidateTag Got %T, Want cache.Invalidator", a)
+		return
+	}
+
+	// subscription is async, invalidate till b sees it
+	timeout := time.After(2 * time.Second)
+	for {
+		time.Sleep(20 * time.Millisecond)
+		a.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("1"), TTL: 60, Tags: []string{"t"}})
+		b.Get(context.Background(), "1")
+
+		if err := inv.InvalidateTag(context.Background(), "t"); err != nil {
+			t.Error(err)
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		if r, _ := b.Get(context.Background(), "1"); r == nil {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Errorf("Error: TestCacheInvalidateTag local tier of peer not flushed")
+			return
+		default:
+		}
+	}
+
+	if r, _ := a.Get(context.Background(), "1"); r != nil {
+		t.Errorf("Error: TestCacheInvalidateTag Got %v, Want nil", r)
+	}
+}
+
+func TestCacheClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := testFarm(t)
+	f.AllConn().Do("FLUSHALL")
+	a := testNearCache(t, ctx, f)
+	b := testNearCache(t, ctx, f)
+
+	// wait till b receives invalidations
+	timeout := time.After(2 * time.Second)
+	for {
+		time.Sleep(20 * time.Millisecond)
+		a.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("old"), TTL: 60})
+
+		if r, _ := b.Get(context.Background(), "1"); r != nil && string(r.Val) == "old" {
+			a.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("new"), TTL: 60})
+			time.Sleep(20 * time.Millisecond)
+			if r, _ := b.Get(context.Background(), "1"); r != nil && string(r.Val) == "new" {
+				break
+			}
+		}
+
+		select {
+		case <-timeout:
+			t.Errorf("Error: TestCacheClose local tier of peer not invalidated")
+			return
+		default:
+		}
+	}
+
+	if err := b.(io.Closer).Close(); err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// b no longer receives invalidations, keeps its local copy
+	a.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("newer"), TTL: 60})
+	time.Sleep(50 * time.Millisecond)
+
+	if r, _ := b.Get(context.Background(), "1"); r == nil || string(r.Val) != "new" {
+		t.Errorf("Error: TestCacheClose Got %v, Want %s", r, "new")
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import "context"
+
+// operations reported to Observer
+const (
+	OpPut              = "put"
+	OpGet              = "get"
+	OpDelete           = "delete"
+	OpMultiGet         = "multi_get"
+	OpMultiPut         = "multi_put"
+	OpMultiDelete      = "multi_delete"
+	OpInvalidateTag    = "invalidate_tag"
+	OpInvalidatePrefix = "invalidate_prefix"
+)
+
+// Done : called with error of an operation when it returns
+type Done func(err error)
+
+// Observer : hooks called by cache operations, for metrics and tracing
+type Observer interface {
+
+	// Start : called before op, returned ctx is passed down and Done is
+	// called when op returns
+	Start(ctx context.Context, op string) (context.Context, Done)
+
+	// Hit : n keys of op were found
+	Hit(ctx context.Context, op string, n int)
+
+	// Miss : n keys of op were not found
+	Miss(ctx context.Context, op string, n int)
+
+	// StaleWrite : n writes of op were refused since items have a newer write
+	StaleWrite(ctx context.Context, op string, n int)
+}
+
+// NopObserver : observer which does nothing, default of caches
+type NopObserver struct{}
+
+// Start :
+func (NopObserver) Start(ctx context.Context, op string) (context.Context, Done) {
+	return ctx, func(error) {}
+}
+
+// Hit :
+func (NopObserver) Hit(ctx context.Context, op string, n int) {}
+
+// Miss :
+func (NopObserver) Miss(ctx context.Context, op string, n int) {}
+
+// StaleWrite :
+func (NopObserver) StaleWrite(ctx context.Context, op string, n int) {}
+
+// observers : calls every observer in order
+type observers []Observer
+
+// Observers : observer reporting to all obs, for example metrics and tracing
+func Observers(obs ...Observer) Observer {
+	return observers(obs)
+}
+
+// Start :
+func (o observers) Start(ctx context.Context, op string) (context.Context, Done) {
+	dones := make([]Done, 0, len(o))
+	for _, v := range o {
+		var done Done
+		ctx, done = v.Start(ctx, op)
+		dones = append(dones, done)
+	}
+
+	return ctx, func(err error) {
+		for i := len(dones) - 1; i >= 0; i-- {
+			dones[i](err)
+		}
+	}
+}
+
+// Hit :
+func (o observers) Hit(ctx context.Context, op string, n int) {
+	for _, v := range o {
+		v.Hit(ctx, op, n)
+	}
+}
+
+// Miss :
+func (o observers) Miss(ctx context.Context, op string, n int) {
+	for _, v := range o {
+		v.Miss(ctx, op, n)
+	}
+}
+
+// StaleWrite :
+func (o observers) StaleWrite(ctx context.Context, op string, n int) {
+	for _, v := range o {
+		v.StaleWrite(ctx, op, n)
+	}
+}
@@ -0,0 +1,51 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alokic/gopkg/cache"
+)
+
+// testObserver : records calls in order
+type testObserver struct {
+	name  string
+	calls *[]string
+}
+
+func (o testObserver) Start(ctx context.Context, op string) (context.Context, cache.Done) {
+	*o.calls = append(*o.calls, o.name+":start:"+op)
+	return ctx, func(err error) {
+		*o.calls = append(*o.calls, o.name+":done:"+err.Error())
+	}
+}
+
+func (o testObserver) Hit(ctx context.Context, op string, n int) {
+	*o.calls = append(*o.calls, o.name+":hit")
+}
+
+func (o testObserver) Miss(ctx context.Context, op string, n int) {}
+
+func (o testObserver) StaleWrite(ctx context.Context, op string, n int) {}
+
+func TestObservers(t *testing.T) {
+	var calls []string
+	o := cache.Observers(testObserver{"a", &calls}, testObserver{"b", &calls})
+
+	_, done := o.Start(context.Background(), cache.OpGet)
+	o.Hit(context.Background(), cache.OpGet, 1)
+	done(errors.New("err"))
+
+	want := []string{"a:start:get", "b:start:get", "a:hit", "b:hit", "b:done:err", "a:done:err"}
+	if len(calls) != len(want) {
+		t.Errorf("Error: TestObservers Got %v, Want %v", calls, want)
+		return
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("Error: TestObservers Got %v, Want %v", calls, want)
+			return
+		}
+	}
+}
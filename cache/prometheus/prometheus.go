@@ -0,0 +1,83 @@
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	"github.com/alokic/gopkg/cache"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// observer : reports cache operations as prometheus metrics
+type observer struct {
+	duration *prom.HistogramVec
+	errors   *prom.CounterVec
+	hits     *prom.CounterVec
+	misses   *prom.CounterVec
+	stale    *prom.CounterVec
+}
+
+// NewObserver : cache observer registering its metrics, prefixed with
+// namespace, on reg. prometheus.DefaultRegisterer is used when reg is nil
+func NewObserver(namespace string, reg prom.Registerer) (cache.Observer, error) {
+	if reg == nil {
+		reg = prom.DefaultRegisterer
+	}
+
+	o := &observer{
+		duration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of cache operations.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"op"}),
+		errors: counterVec(namespace, "errors_total", "Cache operations which failed."),
+		hits:   counterVec(namespace, "hits_total", "Keys found by cache operations."),
+		misses: counterVec(namespace, "misses_total", "Keys not found by cache operations."),
+		stale:  counterVec(namespace, "stale_writes_total", "Writes refused since item has a newer write."),
+	}
+
+	for _, c := range []prom.Collector{o.duration, o.errors, o.hits, o.misses, o.stale} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func counterVec(namespace, name, help string) *prom.CounterVec {
+	return prom.NewCounterVec(prom.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      name,
+		Help:      help,
+	}, []string{"op"})
+}
+
+// Start :
+func (o *observer) Start(ctx context.Context, op string) (context.Context, cache.Done) {
+	start := time.Now()
+
+	return ctx, func(err error) {
+		o.duration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+		if err != nil {
+			o.errors.WithLabelValues(op).Inc()
+		}
+	}
+}
+
+// Hit :
+func (o *observer) Hit(ctx context.Context, op string, n int) {
+	o.hits.WithLabelValues(op).Add(float64(n))
+}
+
+// Miss :
+func (o *observer) Miss(ctx context.Context, op string, n int) {
+	o.misses.WithLabelValues(op).Add(float64(n))
+}
+
+// StaleWrite :
+func (o *observer) StaleWrite(ctx context.Context, op string, n int) {
+	o.stale.WithLabelValues(op).Add(float64(n))
+}
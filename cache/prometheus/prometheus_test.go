@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alokic/gopkg/cache"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func testMetric(t *testing.T, reg *prom.Registry, name, op string) *dto.Metric {
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "op" && l.GetValue() == op {
+					return m
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestObserver(t *testing.T) {
+	reg := prom.NewRegistry()
+
+	o, err := NewObserver("test", reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, done := o.Start(context.Background(), cache.OpGet)
+	o.Hit(context.Background(), cache.OpGet, 2)
+	o.Miss(context.Background(), cache.OpGet, 1)
+	done(errors.New("get failed"))
+	o.StaleWrite(context.Background(), cache.OpMultiPut, 3)
+
+	if m := testMetric(t, reg, "test_cache_operation_duration_seconds", cache.OpGet); m == nil || m.GetHistogram().GetSampleCount() != 1 {
+		t.Errorf("Error: TestObserver Got %v, Want %d observation", m, 1)
+	}
+
+	counters := []struct {
+		name string
+		op   string
+		want float64
+	}{
+		{"test_cache_errors_total", cache.OpGet, 1},
+		{"test_cache_hits_total", cache.OpGet, 2},
+		{"test_cache_misses_total", cache.OpGet, 1},
+		{"test_cache_stale_writes_total", cache.OpMultiPut, 3},
+	}
+	for _, c := range counters {
+		if m := testMetric(t, reg, c.name, c.op); m == nil || m.GetCounter().GetValue() != c.want {
+			t.Errorf("Error: TestObserver %s Got %v, Want %v", c.name, m, c.want)
+		}
+	}
+}
+
+func TestObserverRegisterTwice(t *testing.T) {
+	reg := prom.NewRegistry()
+
+	if _, err := NewObserver("test", reg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewObserver("test", reg); err == nil {
+		t.Errorf("Error: TestObserverRegisterTwice Got %v, Want error", err)
+	}
+}
@@ -16,6 +16,8 @@ type Logger interface {
 	Println(...interface{})
 }
 
+// New : cache on redisServer. Build errors are logged, and a cache whose
+// scripts could not be preloaded is still returned, they are loaded on use
 func New(redisServer, app, prefix string, logger Logger) cache.Cache {
 	if redisServer == "" {
 		logger.Println(ErrRedisUrlNotFound)
@@ -23,25 +25,41 @@ func New(redisServer, app, prefix string, logger Logger) cache.Cache {
 	}
 
 	// build cluster
-	cl, _ := farm.
+	cl, err := farm.
 		NewClusterBuilder().
 		SetMaxIdleConns(6).
 		SetMaxActiveConns(6).
 		SetServers([]string{redisServer}).
 		Build()
+	logOnError(err, logger)
 
 	// build farm
-	f, _ := farm.
+	f, err := farm.
 		NewBuilder().
 		SetCluster([]*farm.Cluster{cl}).
 		Build()
+	logOnError(err, logger)
 
 	// build cache
-	c, _ := NewCacheBuilder().
+	b := NewCacheBuilder().
 		SetFarm(f).
 		SetApp(app).
-		SetPrefix(prefix).
-		Build()
+		SetPrefix(prefix)
+
+	c, err := b.Build()
+	if err != nil {
+		logger.Println(err)
+		if f != nil {
+			// degraded till redis is reachable
+			return b.rs
+		}
+	}
 
 	return c
 }
+
+func logOnError(err error, logger Logger) {
+	if err != nil {
+		logger.Println(err)
+	}
+}
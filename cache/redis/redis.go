@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -14,6 +15,61 @@ import (
 )
 
 var (
+	// ErrCacheFarmNotFound : farm is not set
+	ErrCacheFarmNotFound = errors.New("Cache: farm not found")
+
+	// ErrCacheTagsClusterMode : tag index of a node can not be kept in native Redis Cluster
+	ErrCacheTagsClusterMode = errors.New("Cache: tags are not supported in cluster mode")
+
+	// cacheSetTagsScriptStr : prepended to put scripts, with expiry of item
+	// hash. Item hash keeps its tags, so that it is removed from index of tags
+	// it no longer has
+	cacheSetTagsScriptStr = `
+		local keyTags = 'KEYTAGS'
+
+		-- TTL of 0 or less never expires, like memory cache
+		local function expire(hkey, ttl)
+			if ttl > 0 then
+				redis.call("EXPIRE", hkey, ttl)
+			else
+				redis.call("PERSIST", hkey)
+			end
+		end
+
+		local function setTags(hkey, ttl, tagPrefix, tags)
+			local keep = {}
+			for _, t in ipairs(tags) do
+				keep[t] = true
+			end
+
+			local old = redis.call("HGET", hkey, keyTags)
+			if old then
+				for _, t in ipairs(cjson.decode(old)) do
+					if not keep[t] then
+						redis.call("SREM", tagPrefix .. t, hkey)
+					end
+				end
+			end
+
+			if #tags == 0 then
+				redis.call("HDEL", hkey, keyTags)
+				return
+			end
+
+			redis.call("HSET", hkey, keyTags, cjson.encode(tags))
+			local cur
+			for _, t in ipairs(tags) do
+				cur = redis.call("TTL", tagPrefix .. t) -- -2 for a new index, -1 when an item never expires
+				redis.call("SADD", tagPrefix .. t, hkey)
+				if ttl <= 0 then
+					redis.call("PERSIST", tagPrefix .. t)
+				elseif cur ~= -1 and cur < ttl then -- index lives as long as its items
+					redis.call("EXPIRE", tagPrefix .. t, ttl)
+				end
+			end
+		end
+	`
+
 	cachePutScriptStr = `
 		local keyTimestamp = 'KEYTIMESTAMP'
 		local tms = redis.call("HGET", ARGV[1], keyTimestamp)
@@ -23,8 +79,9 @@ var (
 		if tms == false or in_tms > tonumber(tms) then
 		    redis.call("HSET", ARGV[1], keyTimestamp, in_tms)
 			redis.call("HSET", ARGV[1], ARGV[3], ARGV[4])
-			redis.call("EXPIRE", ARGV[1], ARGV[5])
+			expire(ARGV[1], ARGV[5] + 0)
 			redis.call("HDEL", ARGV[1], keyDeleted) -- delete 'keyDeleted' marker if deleted is put before Expiry
+			setTags(ARGV[1], ARGV[5] + 0, ARGV[6], {unpack(ARGV, 7)})
 		end
 
 		return tms
@@ -79,14 +136,14 @@ var (
 		local keyTTL = 'KEYTTL'
 		local keyDeleted = 'KEYDELETED'
 
-		local is_deleted
 		local tbl = {}
 
-    	for i = 1, #ARGV, 1 do
-		  if redis.call("EXISTS", ARGV[i]) == 1 then
+		for i = 1, #ARGV, 1 do
+			tbl[i] = {} -- empty for a missing key, so that reply is aligned with keys
+			if redis.call("EXISTS", ARGV[i]) == 1 then
 				if redis.call("HGET", ARGV[i], keyDeleted) == false then
 					redis.call("HSET", ARGV[i], keyTTL, redis.call("TTL", ARGV[i])) -- set ttl in map to return
-					tbl[#tbl+1] = redis.call("HGETALL", ARGV[i])
+					tbl[i] = redis.call("HGETALL", ARGV[i])
 					redis.call("HDEL", ARGV[i], keyTTL)  -- remove ttl from map as its not needed
 				end
 			end
@@ -94,6 +151,58 @@ var (
 
 		return tbl
 	`
+
+	cacheMultiPutScriptStr = `
+		local keyTimestamp = 'KEYTIMESTAMP'
+		local keyDeleted = 'KEYDELETED'
+		local in_tms = ARGV[1] + 0
+		local tagPrefix = ARGV[2]
+
+		local tms
+		local ntags
+		local i = 3
+		local stale = {} -- 1 for a write refused since item has a newer write
+
+		-- ARGV[i .. i+4+ntags]: formatted key, key, value, ttl, ntags, tags
+		while i <= #ARGV do
+			ntags = ARGV[i+4] + 0
+			tms = redis.call("HGET", ARGV[i], keyTimestamp)
+			if tms == false or in_tms > tonumber(tms) then
+				redis.call("HSET", ARGV[i], keyTimestamp, in_tms)
+				redis.call("HSET", ARGV[i], ARGV[i+1], ARGV[i+2])
+				expire(ARGV[i], ARGV[i+3] + 0)
+				redis.call("HDEL", ARGV[i], keyDeleted)
+				setTags(ARGV[i], ARGV[i+3] + 0, tagPrefix, {unpack(ARGV, i+5, i+4+ntags)})
+				stale[#stale+1] = 0
+			else
+				stale[#stale+1] = 1
+			end
+			i = i + 5 + ntags
+		end
+
+		return stale
+	`
+
+	cacheInvalidateTagScriptStr = `
+		local keyTimestamp = 'KEYTIMESTAMP'
+		local keyDeleted = 'KEYDELETED'
+		local in_tms = ARGV[2] + 0
+
+		local tms
+		local members = redis.call("SMEMBERS", ARGV[1])
+
+		for _, k in ipairs(members) do
+			tms = redis.call("HGET", k, keyTimestamp)
+			if tms == false then
+				redis.call("SREM", ARGV[1], k) -- expired
+			elseif in_tms >= tonumber(tms) then
+				redis.call("HSET", k, keyDeleted, 1)
+				redis.call("SREM", ARGV[1], k)
+			end
+		end
+
+		return #members
+	`
 	cacheMultiDeleteScriptStr = `
 		local keyTTL = 'KEYTTL'
 		local keyTimestamp = 'KEYTIMESTAMP'
@@ -116,23 +225,47 @@ var (
 
 		return tbl
 	`
-	cachePutScript         *redis.Script
-	cacheGetScript         *redis.Script
-	cacheDeleteScript      *redis.Script
-	cacheMultiGetScript    *redis.Script
-	cacheMultiDeleteScript *redis.Script
+	// cacheScripts : source of every script of a cache instance by name.
+	// Put scripts need setTags function
+	cacheScripts = map[string]string{
+		scriptPut:           cacheSetTagsScriptStr + cachePutScriptStr,
+		scriptGet:           cacheGetScriptStr,
+		scriptDelete:        cacheDeleteScriptStr,
+		scriptMultiGet:      cacheMultiGetScriptStr,
+		scriptMultiPut:      cacheSetTagsScriptStr + cacheMultiPutScriptStr,
+		scriptMultiDelete:   cacheMultiDeleteScriptStr,
+		scriptInvalidateTag: cacheInvalidateTagScriptStr,
+	}
 
-	cacheMaxListSize = 25
+	// keys sent in one script call by multi key operations
+	cacheBatchSize = 25
 
 	keyTimestamp   = "_tm_"
 	keyTTL         = "_ttl_"
 	keyDeleted     = "_del_"
+	keyTags        = "_tags_"
 	keyspacePrefix = "h"
 
+	// tag index keys follow keyspace with it, so that a key prefix never matches them
+	tagKeySeparator = "#tag:"
+
+	// keys asked per SCAN call
+	scanCount = 500
+
 	// only for test simulation
 	timeLag = int64(0)
 )
 
+const (
+	scriptPut           = "put"
+	scriptGet           = "get"
+	scriptDelete        = "delete"
+	scriptMultiGet      = "multiGet"
+	scriptMultiPut      = "multiPut"
+	scriptMultiDelete   = "multiDelete"
+	scriptInvalidateTag = "invalidateTag"
+)
+
 // CacheBuilder :
 type CacheBuilder struct {
 	rs *redisCache
@@ -145,6 +278,7 @@ type redisCache struct {
 	app      string
 	keyspace string
 	script   map[string]*redis.Script
+	observer cache.Observer
 }
 
 // NewCacheBuilder :
@@ -170,16 +304,26 @@ func (c *CacheBuilder) SetPrefix(name string) *CacheBuilder {
 	return c
 }
 
+// SetObserver : observer of hits, misses, stale writes, errors and latency
+func (c *CacheBuilder) SetObserver(o cache.Observer) *CacheBuilder {
+	c.rs.observer = o
+	return c
+}
+
 // Build :
 func (c *CacheBuilder) Build() (cache.Cache, error) {
 	return c.rs.build()
 }
 
 func newCache() *redisCache {
-	return &redisCache{script: make(map[string]*redis.Script)}
+	return &redisCache{script: make(map[string]*redis.Script), observer: cache.NopObserver{}}
 }
 
 func (c *redisCache) build() (cache.Cache, error) {
+	if c.farm == nil {
+		return nil, ErrCacheFarmNotFound
+	}
+
 	c.keyspace = fmt.Sprintf("%s:%s:%s", keyspacePrefix, c.app, c.prefix)
 	if c.prefix != "" {
 		c.keyspace = fmt.Sprintf("%s:%s", c.keyspace, c.prefix)
@@ -193,130 +337,287 @@ func (c *redisCache) build() (cache.Cache, error) {
 	return c, nil
 }
 
-// Put : put a item
-func (c *redisCache) Put(ctx context.Context, r *cache.Item) (interface{}, error) {
-	formattedKey := c.formatKey(r.Key)
+// Put : put a item, TTL of 0 or less never expires
+func (c *redisCache) Put(ctx context.Context, r *cache.Item) (reply interface{}, err error) {
+	ctx, done := c.observer.Start(ctx, cache.OpPut)
+	defer func() { done(err) }()
 
-	// get connection based on formattedKey
-	conn := c.farm.GetConn(formattedKey)
+	if len(r.Tags) > 0 && c.farm.ClusterMode() {
+		return nil, ErrCacheTagsClusterMode
+	}
 
-	arr := []interface{}{formattedKey, unixTime(), r.Key, r.Val, r.TTL}
+	formattedKey := c.formatKey(r.Key)
+	tms := unixTime()
 
-	reply, err := cachePutScript.Do(conn, arr...)
+	arr := []interface{}{formattedKey, tms, r.Key, r.Val, r.TTL, c.tagKeyPrefix()}
+	for _, v := range r.Tags {
+		arr = append(arr, v)
+	}
 
-	// since its of type redis.Error, nil check fails. So always need to check for blank string
-	if err.Error() != "" {
-		return nil, errors.New(err.Error())
+	// write to every cluster, succeeds when write quorum acks
+	reply, err = c.farm.Write(formattedKey, func(conn redis.Conn) (interface{}, error) {
+		return farm.DoScript(conn, c.script[scriptPut], arr...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// reply is timestamp of previous write, refused unless older
+	if b, ok := reply.([]byte); ok && typeutils.ToInt64(string(b)) >= tms {
+		c.observer.StaleWrite(ctx, cache.OpPut, 1)
 	}
 
 	return reply, nil
 }
 
 // Get : get a item
-func (c *redisCache) Get(ctx context.Context, key string) (*cache.Item, error) {
-	formattedKey := c.formatKey(key)
+func (c *redisCache) Get(ctx context.Context, key string) (item *cache.Item, err error) {
+	ctx, done := c.observer.Start(ctx, cache.OpGet)
+	defer func() { done(err) }()
 
-	// get connection based on formattedKey
-	conn := c.farm.GetConn(formattedKey)
-
-	// splat the args..
-	reply, err := cacheGetScript.Do(conn, formattedKey)
+	formattedKey := c.formatKey(key)
 
-	// since its of type redis.Error, nil check fails. So always need to check for blank string
-	if err.Error() != "" {
-		return nil, errors.New(err.Error())
+	// read from first cluster which serves the key
+	reply, err := c.farm.Read(formattedKey, func(conn redis.Conn) (interface{}, error) {
+		return farm.DoScript(conn, c.script[scriptGet], formattedKey)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var items []*cache.Item
-
-	for _, e := range reply.([]interface{}) {
-		if len(e.([]interface{})) == 0 {
-			continue
-		}
-		items = append(items, c.unmarshallItem(e.([]interface{})))
+	e := reply.([]interface{})
+	if len(e) == 0 {
+		c.observer.Miss(ctx, cache.OpGet, 1)
+		return nil, nil
 	}
 
-	if len(items) > 0 {
-		return items[0], nil
-	}
-	return nil, nil
+	c.observer.Hit(ctx, cache.OpGet, 1)
+	return c.unmarshallItem(e), nil
 }
 
 // Delete : delete a item based on ID
-func (c *redisCache) Delete(ctx context.Context, key string) error {
-	formattedKey := c.formatKey(key)
+func (c *redisCache) Delete(ctx context.Context, key string) (err error) {
+	ctx, done := c.observer.Start(ctx, cache.OpDelete)
+	defer func() { done(err) }()
 
-	// get connection based on formattedKey
-	conn := c.farm.GetConn(formattedKey)
+	formattedKey := c.formatKey(key)
 
 	arr := []interface{}{formattedKey, unixTime()}
-	// splat the args..
 
-	_, err := cacheDeleteScript.Do(conn, arr...)
+	// write to every cluster, succeeds when write quorum acks
+	_, err = c.farm.Write(formattedKey, func(conn redis.Conn) (interface{}, error) {
+		return farm.DoScript(conn, c.script[scriptDelete], arr...)
+	})
 
-	// since its of type redis.Error, nil check fails. So always need to check for blank string
-	if err.Error() != "" {
-		return errors.New(err.Error())
-	}
-
-	return nil
+	return err
 }
 
-// MultiGet : Get multiple keys
-//   Maximum of cacheMaxListSize is accepted
-func (c *redisCache) MultiGet(ctx context.Context, keys []string) ([]*cache.Item, error) {
-	conn := c.farm.AllConn()
+// MultiGet : Get multiple keys.
+// Items are aligned with keys, nil for a missing key
+func (c *redisCache) MultiGet(ctx context.Context, keys []string) (_ []*cache.Item, err error) {
+	ctx, done := c.observer.Start(ctx, cache.OpMultiGet)
+	defer func() { done(err) }()
+
+	formattedKeys := c.formatKeys(keys)
+	items := make([]*cache.Item, len(keys))
+
+	// one script call per shard with its keys, shards in parallel
+	err = c.farm.ReadKeys(formattedKeys, func(conn redis.Conn, idx []int) error {
+		return batch(len(idx), func(start, end int) error {
+			arr := []interface{}{}
+			for _, i := range idx[start:end] {
+				arr = append(arr, formattedKeys[i])
+			}
 
-	if len(keys) > cacheMaxListSize {
-		keys = keys[:cacheMaxListSize]
+			// splat the args..
+			reply, err := farm.DoScript(conn, c.script[scriptMultiGet], arr...)
+			if err != nil {
+				return err
+			}
+
+			// reply is aligned with keys
+			for i, e := range reply.([]interface{}) {
+				if len(e.([]interface{})) == 0 {
+					continue
+				}
+				items[idx[start+i]] = c.unmarshallItem(e.([]interface{}))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	arr := []interface{}{}
-	for _, v := range keys {
-		arr = append(arr, c.formatKey(v))
+	hits := 0
+	for _, v := range items {
+		if v != nil {
+			hits++
+		}
 	}
+	c.observer.Hit(ctx, cache.OpMultiGet, hits)
+	c.observer.Miss(ctx, cache.OpMultiGet, len(items)-hits)
 
-	// splat the args..
-	reply, err := cacheMultiGetScript.Do(conn, arr...)
+	return items, nil
+}
 
-	// since its of type redis.Error, nil check fails. So always need to check for blank string
-	if err.Error() != "" {
-		return nil, errors.New(err.Error())
+// MultiPut : put multiple items
+func (c *redisCache) MultiPut(ctx context.Context, items []*cache.Item) (err error) {
+	ctx, done := c.observer.Start(ctx, cache.OpMultiPut)
+	defer func() { done(err) }()
+
+	keys := make([]string, 0, len(items))
+	for _, v := range items {
+		if len(v.Tags) > 0 && c.farm.ClusterMode() {
+			return ErrCacheTagsClusterMode
+		}
+		keys = append(keys, v.Key)
 	}
+	formattedKeys := c.formatKeys(keys)
+	tms := unixTime()
+
+	// stale[i] is set when any cluster refused write of items[i]
+	stale := make([]bool, len(items))
+
+	// write to every cluster, succeeds when write quorum acks
+	err = c.farm.WriteKeys(formattedKeys, func(conn redis.Conn, idx []int) error {
+		return batch(len(idx), func(start, end int) error {
+			arr := []interface{}{tms, c.tagKeyPrefix()}
+			for _, i := range idx[start:end] {
+				arr = append(arr, formattedKeys[i], items[i].Key, items[i].Val, items[i].TTL, len(items[i].Tags))
+				for _, v := range items[i].Tags {
+					arr = append(arr, v)
+				}
+			}
+
+			// splat the args..
+			flags, err := redis.Ints(farm.DoScript(conn, c.script[scriptMultiPut], arr...))
+			if err != nil {
+				return err
+			}
 
-	var items []*cache.Item
-	for _, elements := range reply.([]interface{}) {
-		for _, e := range elements.([]interface{}) {
-			if len(e.([]interface{})) == 0 {
-				continue
+			for i, v := range flags {
+				if v == 1 {
+					stale[idx[start+i]] = true
+				}
 			}
-			items = append(items, c.unmarshallItem(e.([]interface{})))
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for _, v := range stale {
+		if v {
+			n++
 		}
 	}
+	if n > 0 {
+		c.observer.StaleWrite(ctx, cache.OpMultiPut, n)
+	}
 
-	return items, nil
+	return nil
 }
 
 // MultiDelete : Delete multiple keys
-//   Maximum of cacheMaxListSize is accepted
-func (c *redisCache) MultiDelete(ctx context.Context, keys []string) error {
-	conn := c.farm.AllConn()
+func (c *redisCache) MultiDelete(ctx context.Context, keys []string) (err error) {
+	ctx, done := c.observer.Start(ctx, cache.OpMultiDelete)
+	defer func() { done(err) }()
+
+	formattedKeys := c.formatKeys(keys)
+	tms := unixTime()
+
+	// write to every cluster, succeeds when write quorum acks
+	return c.farm.WriteKeys(formattedKeys, func(conn redis.Conn, idx []int) error {
+		return batch(len(idx), func(start, end int) error {
+			arr := []interface{}{tms}
+			for _, i := range idx[start:end] {
+				arr = append(arr, formattedKeys[i])
+			}
 
-	if len(keys) > cacheMaxListSize {
-		keys = keys[:cacheMaxListSize]
-	}
+			// splat the args..
+			_, err := farm.DoScript(conn, c.script[scriptMultiDelete], arr...)
+			return err
+		})
+	})
+}
 
-	arr := []interface{}{unixTime()}
-	for _, v := range keys {
-		arr = append(arr, c.formatKey(v))
+// InvalidateTag : delete all items put with tag.
+// Every server keeps index of its own items, invalidated atomically on each.
+// Index key of a tag lives on one node of native Redis Cluster, so tags fail
+// with ErrCacheTagsClusterMode there
+func (c *redisCache) InvalidateTag(ctx context.Context, tag string) (err error) {
+	ctx, done := c.observer.Start(ctx, cache.OpInvalidateTag)
+	defer func() { done(err) }()
+
+	if c.farm.ClusterMode() {
+		return ErrCacheTagsClusterMode
 	}
 
-	// splat the args..
-	_, err := cacheMultiDeleteScript.Do(conn, arr...)
+	arr := []interface{}{c.tagKeyPrefix() + tag, unixTime()}
+
+	// write to every server of every cluster, succeeds when write quorum acks
+	return c.farm.WriteAll(func(conn redis.Conn) (interface{}, error) {
+		return farm.DoScript(conn, c.script[scriptInvalidateTag], arr...)
+	})
+}
+
+// InvalidatePrefix : delete all items whose key starts with prefix.
+// Keys are found by SCAN on every server
+func (c *redisCache) InvalidatePrefix(ctx context.Context, prefix string) (err error) {
+	ctx, done := c.observer.Start(ctx, cache.OpInvalidatePrefix)
+	defer func() { done(err) }()
+
+	match := globEscape(c.formatKey(prefix)) + "*"
+	tms := unixTime()
+
+	// write to every server of every cluster, succeeds when write quorum acks
+	return c.farm.WriteAll(func(conn redis.Conn) (interface{}, error) {
+		cursor := int64(0)
+		for {
+			reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", match, "COUNT", scanCount))
+			if err != nil {
+				return nil, err
+			}
+
+			var keys []string
+			if _, err := redis.Scan(reply, &cursor, &keys); err != nil {
+				return nil, err
+			}
+
+			err = batch(len(keys), func(start, end int) error {
+				arr := []interface{}{tms}
+				for _, v := range keys[start:end] {
+					arr = append(arr, v)
+				}
+
+				_, err := farm.DoScript(conn, c.script[scriptMultiDelete], arr...)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if cursor == 0 {
+				return nil, nil
+			}
+		}
+	})
+}
+
+// batch : calls fn for consecutive ranges of n elements, of at most cacheBatchSize
+func batch(n int, fn func(start, end int) error) error {
+	for start := 0; start < n; start += cacheBatchSize {
+		end := start + cacheBatchSize
+		if end > n {
+			end = n
+		}
 
-	// since its of type redis.Error, nil check fails. So always need to check for blank string
-	if err.Error() != "" {
-		return errors.New(err.Error())
+		if err := fn(start, end); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -332,8 +633,10 @@ func (c *redisCache) unmarshallItem(arr []interface{}) *cache.Item {
 		switch k {
 		case keyTimestamp:
 		case keyDeleted:
+		case keyTags:
+			json.Unmarshal(arr[i+1].([]byte), &r.Tags)
 		case keyTTL:
-			r.TTL = typeutils.ToInt64(arr[i+1].([]byte))
+			r.TTL = typeutils.ToInt64(string(arr[i+1].([]byte)))
 		default:
 			r.Key = k
 			r.Val = arr[i+1].([]byte)
@@ -344,32 +647,43 @@ func (c *redisCache) unmarshallItem(arr []interface{}) *cache.Item {
 	return r
 }
 
+// loadScript : scripts of this instance, with placeholders of field names
+// replaced. Scripts are loaded on every server, so that a bad script or an
+// unreachable farm is reported at Build
 func (c *redisCache) loadScript() error {
-	cachePutScript = redis.NewScript(0, strings.
-		NewReplacer("KEYTTL", keyTTL, "KEYTIMESTAMP", keyTimestamp, "KEYDELETED", keyDeleted).
-		Replace(cachePutScriptStr))
-
-	cacheGetScript = redis.NewScript(0, strings.
-		NewReplacer("KEYDELETED", keyDeleted, "KEYTTL", keyTTL).
-		Replace(cacheGetScriptStr))
+	r := strings.NewReplacer(
+		"KEYTTL", keyTTL,
+		"KEYTIMESTAMP", keyTimestamp,
+		"KEYDELETED", keyDeleted,
+		"KEYTAGS", keyTags,
+	)
+
+	for name, src := range cacheScripts {
+		c.script[name] = redis.NewScript(0, r.Replace(src))
+	}
 
-	cacheDeleteScript = redis.NewScript(0, strings.
-		NewReplacer("KEYTIMESTAMP", keyTimestamp, "KEYDELETED", keyDeleted, "KEYTTL", keyTTL).
-		Replace(cacheDeleteScriptStr))
+	return c.farm.LoadScripts(c.script)
+}
 
-	cacheMultiGetScript = redis.NewScript(0, strings.
-		NewReplacer("KEYDELETED", keyDeleted, "KEYTTL", keyTTL).
-		Replace(cacheMultiGetScriptStr))
+func (c *redisCache) formatKey(key string) string {
+	return fmt.Sprintf("%s:%s", c.keyspace, key)
+}
 
-	cacheMultiDeleteScript = redis.NewScript(0, strings.
-		NewReplacer("KEYDELETED", keyDeleted, "KEYTTL", keyTTL).
-		Replace(cacheMultiDeleteScriptStr))
+func (c *redisCache) tagKeyPrefix() string {
+	return c.keyspace + tagKeySeparator
+}
 
-	return nil
+// globEscape : escape glob special chars of SCAN MATCH pattern
+func globEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
 }
 
-func (c *redisCache) formatKey(key string) string {
-	return fmt.Sprintf("%s:%s", c.keyspace, key)
+func (c *redisCache) formatKeys(keys []string) []string {
+	formattedKeys := make([]string, 0, len(keys))
+	for _, v := range keys {
+		formattedKeys = append(formattedKeys, c.formatKey(v))
+	}
+	return formattedKeys
 }
 
 // SetTimeDiffForTesting : clock adjustment is needed
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"testing"
 
 	"github.com/alokic/gopkg/cache"
+	"github.com/alokic/gopkg/cache/cachetest"
 	farm "github.com/alokic/gopkg/redisfarm"
+	"github.com/garyburd/redigo/redis"
 )
 
 type TestConfig struct {
@@ -263,8 +266,8 @@ func TestCacheMultiGetNoDeletedItem(t *testing.T) {
 		return
 	}
 
-	if len(r) != numRec-1 {
-		t.Errorf("Items should be returned in multiGet operation")
+	if len(r) != numRec || r[1] != nil {
+		t.Errorf("Deleted item should be nil in multiGet operation")
 		return
 	}
 
@@ -304,6 +307,250 @@ func TestCacheMultiDelete(t *testing.T) {
 	log.Println("Pass:TestCacheMultiGetNoDeletedItem: ")
 }
 
+func TestCacheInvalidateTag(t *testing.T) {
+	c := testCacheCreate()
+	testCacheClearData()
+	SetTimeDiffForTesting(0)
+	ctx := context.Background()
+
+	c.Put(ctx, &cache.Item{Key: "1", Val: []byte("1"), TTL: 60, Tags: []string{"user:42"}})
+	c.MultiPut(ctx, []*cache.Item{
+		{Key: "2", Val: []byte("2"), TTL: 60, Tags: []string{"user:42", "team:1"}},
+		{Key: "3", Val: []byte("3"), TTL: 60, Tags: []string{"team:1"}},
+	})
+
+	// 4 is re-put without the tag
+	c.Put(ctx, &cache.Item{Key: "4", Val: []byte("4"), TTL: 60, Tags: []string{"user:42"}})
+	SetTimeDiffForTesting(10)
+	c.Put(ctx, &cache.Item{Key: "4", Val: []byte("4"), TTL: 60})
+
+	item, _ := c.Get(ctx, "2")
+	if item == nil || len(item.Tags) != 2 || item.Tags[0] != "user:42" {
+		t.Errorf("Error: TestCacheInvalidateTag Got %v, Want tags of item", item)
+		return
+	}
+
+	if err := c.(cache.Invalidator).InvalidateTag(ctx, "user:42"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	items, _ := c.MultiGet(ctx, []string{"1", "2", "3", "4"})
+	if items[0] != nil || items[1] != nil || items[2] == nil || items[3] == nil {
+		t.Errorf("Error: TestCacheInvalidateTag Got %v, Want only items tagged user:42 deleted", items)
+	}
+}
+
+func TestCacheInvalidateTagNewerWrite(t *testing.T) {
+	c := testCacheCreate()
+	testCacheClearData()
+	ctx := context.Background()
+
+	// put is newer than invalidation
+	SetTimeDiffForTesting(10000)
+	c.Put(ctx, &cache.Item{Key: "1", Val: []byte("1"), TTL: 60, Tags: []string{"user:42"}})
+	SetTimeDiffForTesting(0)
+
+	if err := c.(cache.Invalidator).InvalidateTag(ctx, "user:42"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if item, _ := c.Get(ctx, "1"); item == nil {
+		t.Errorf("Error: TestCacheInvalidateTagNewerWrite newer item should not be deleted")
+	}
+}
+
+func TestCacheInvalidatePrefix(t *testing.T) {
+	c := testCacheCreate()
+	testCacheClearData()
+	SetTimeDiffForTesting(0)
+	ctx := context.Background()
+
+	for _, k := range []string{"user:1", "user:2", "user*", "order:1"} {
+		c.Put(ctx, &cache.Item{Key: k, Val: []byte(k), TTL: 60, Tags: []string{"user:"}})
+	}
+
+	if err := c.(cache.Invalidator).InvalidatePrefix(ctx, "user:"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	items, _ := c.MultiGet(ctx, []string{"user:1", "user:2", "user*", "order:1"})
+	if items[0] != nil || items[1] != nil || items[2] == nil || items[3] == nil {
+		t.Errorf("Error: TestCacheInvalidatePrefix Got %v, Want only items with prefix deleted", items)
+	}
+}
+
+func TestCacheBuildErrors(t *testing.T) {
+	if _, err := NewCacheBuilder().SetApp("test").Build(); err != ErrCacheFarmNotFound {
+		t.Errorf("Error: TestCacheBuildErrors Got %v, Want %v", err, ErrCacheFarmNotFound)
+	}
+
+	cl, _ := farm.NewClusterBuilder().SetServers([]string{"redis://localhost:1"}).Build()
+	f, _ := farm.NewBuilder().SetCluster([]*farm.Cluster{cl}).Build()
+
+	if _, err := NewCacheBuilder().SetFarm(f).SetApp("test").Build(); err == nil {
+		t.Errorf("Error: TestCacheBuildErrors unreachable farm should fail build")
+	}
+}
+
+func TestCacheScriptsLoaded(t *testing.T) {
+	a := testCacheCreate().(*redisCache)
+	b := testCacheCreate().(*redisCache)
+
+	if a.script[scriptPut] == b.script[scriptPut] {
+		t.Errorf("Error: TestCacheScriptsLoaded scripts should not be shared between instances")
+	}
+
+	for name, s := range a.script {
+		exists, err := redis.Values(a.farm.GetConn("").Do("SCRIPT", "EXISTS", s.Hash()))
+		if err != nil || len(exists) != 1 {
+			t.Errorf("Error: TestCacheScriptsLoaded Got %v %v", exists, err)
+			continue
+		}
+		if v, _ := redis.Ints(exists[0], nil); len(v) != 1 || v[0] != 1 {
+			t.Errorf("Error: TestCacheScriptsLoaded script %s not loaded", name)
+		}
+	}
+}
+
+func TestCacheMultiDeleteOldOperation(t *testing.T) {
+	c := testCacheCreate()
+	testCacheClearData()
+	ctx := context.Background()
+
+	SetTimeDiffForTesting(10000)
+	c.Put(ctx, &cache.Item{Key: "1", Val: []byte("1"), TTL: 60})
+	SetTimeDiffForTesting(0)
+
+	if err := c.MultiDelete(ctx, []string{"1"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if item, _ := c.Get(ctx, "1"); item == nil {
+		t.Errorf("Error: TestCacheMultiDeleteOldOperation newer item should not be deleted")
+	}
+}
+
+// testObserver : counts events by op
+type testObserver struct {
+	mu     sync.Mutex
+	events map[string]int
+}
+
+func (o *testObserver) add(event, op string, n int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events[event+":"+op] += n
+}
+
+func (o *testObserver) Start(ctx context.Context, op string) (context.Context, cache.Done) {
+	o.add("start", op, 1)
+	return ctx, func(err error) {
+		o.add("done", op, 1)
+	}
+}
+
+func (o *testObserver) Hit(ctx context.Context, op string, n int)  { o.add("hit", op, n) }
+func (o *testObserver) Miss(ctx context.Context, op string, n int) { o.add("miss", op, n) }
+func (o *testObserver) StaleWrite(ctx context.Context, op string, n int) {
+	o.add("stale", op, n)
+}
+
+func TestCacheObserver(t *testing.T) {
+	o := &testObserver{events: map[string]int{}}
+	c, err := NewCacheBuilder().SetFarm(testCacheFarm()).SetApp("test").SetObserver(o).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCacheClearData()
+	ctx := context.Background()
+
+	SetTimeDiffForTesting(10000)
+	c.Put(ctx, &cache.Item{Key: "1", Val: []byte("1"), TTL: 60})
+	SetTimeDiffForTesting(0)
+
+	// older than existing write
+	c.Put(ctx, &cache.Item{Key: "1", Val: []byte("old"), TTL: 60})
+	c.MultiPut(ctx, []*cache.Item{{Key: "1", Val: []byte("old"), TTL: 60}, {Key: "2", Val: []byte("2"), TTL: 60}})
+
+	c.Get(ctx, "1")
+	c.Get(ctx, "notexist")
+	c.MultiGet(ctx, []string{"1", "2", "notexist"})
+
+	want := map[string]int{
+		"start:put": 2, "done:put": 2, "stale:put": 1,
+		"start:multi_put": 1, "done:multi_put": 1, "stale:multi_put": 1,
+		"hit:get": 1, "miss:get": 1,
+		"hit:multi_get": 2, "miss:multi_get": 1,
+	}
+	for k, v := range want {
+		if o.events[k] != v {
+			t.Errorf("Error: TestCacheObserver %s Got %d, Want %d", k, o.events[k], v)
+		}
+	}
+}
+
+func TestCacheTagsClusterMode(t *testing.T) {
+	cl, err := farm.NewClusterBuilder().SetServers([]string{testConfig.server}).SetClusterMode(true).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := farm.NewBuilder().SetCluster([]*farm.Cluster{cl}).Build()
+
+	c, err := NewCacheBuilder().SetFarm(f).SetApp("test").SetPrefix("prefix").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv := c.(cache.Invalidator)
+
+	if _, err := c.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("1"), TTL: 60, Tags: []string{"t"}}); err != ErrCacheTagsClusterMode {
+		t.Errorf("Error: TestCacheTagsClusterMode Got %v, Want %v", err, ErrCacheTagsClusterMode)
+	}
+	if err := c.MultiPut(context.Background(), []*cache.Item{{Key: "1", Val: []byte("1"), TTL: 60, Tags: []string{"t"}}}); err != ErrCacheTagsClusterMode {
+		t.Errorf("Error: TestCacheTagsClusterMode Got %v, Want %v", err, ErrCacheTagsClusterMode)
+	}
+	if err := inv.InvalidateTag(context.Background(), "t"); err != ErrCacheTagsClusterMode {
+		t.Errorf("Error: TestCacheTagsClusterMode Got %v, Want %v", err, ErrCacheTagsClusterMode)
+	}
+
+	// items without tags are still cached
+	if _, err := c.Put(context.Background(), &cache.Item{Key: "1", Val: []byte("1"), TTL: 60}); err != nil {
+		t.Errorf("Error: TestCacheTagsClusterMode Got %v, Want %v", err, nil)
+	}
+}
+
+func TestCacheSuite(t *testing.T) {
+	cachetest.Run(t, func() cache.Cache {
+		testCacheClearData()
+		return testCacheCreate()
+	})
+}
+
 func TestMain(m *testing.M) {
 	m.Run()
 }
+
+func TestNewServerDown(t *testing.T) {
+	var logged []interface{}
+	c := New("redis://localhost:1", "test", "prefix", testLogger(func(v ...interface{}) {
+		logged = append(logged, v...)
+	}))
+
+	if c == nil || len(logged) == 0 {
+		t.Errorf("Error: TestNewServerDown Got %v %v, Want degraded cache and logged error", c, logged)
+		return
+	}
+
+	if _, err := c.Get(context.Background(), "1"); err == nil {
+		t.Errorf("Error: TestNewServerDown Got %v, Want error", err)
+	}
+}
+
+type testLogger func(...interface{})
+
+func (l testLogger) Println(v ...interface{}) {
+	l(v...)
+}
@@ -10,16 +10,6 @@ import (
 	"github.com/garyburd/redigo/redis"
 )
 
-var (
-	idBytes                = "id"
-	sortKeyBytes           = "sort_key"
-	expireAtBytes          = "ttl"
-	runAtBytes             = "run_at"
-	visibilityTimeoutBytes = "vis_time"
-	retryCountBytes        = "retry_count"
-	recordBytes            = "record"
-)
-
 // readScript : Get the script object
 func readScript(name string) (*redis.Script, error) {
 	_, filename, _, ok := runtime.Caller(4)
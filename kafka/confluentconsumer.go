@@ -1,3 +1,6 @@
+//go:build cgo
+// +build cgo
+
 package kafka
 
 import (
@@ -10,23 +13,19 @@ import (
 )
 
 const (
-	offsetReset  = "earliest"
-	commitPeriod = 5 * time.Second
-	drainPeriod  = 2 * time.Second
+	offsetReset = "earliest"
 	//minQueued    = 100
 	//maxKbQueued  = 10 * 1024 //higher priority
 )
 
-type TopicPartition struct {
-	Topic     string
-	Partition int32
-}
-
 type confluentConsumer struct {
 	name         string
 	brokers      []string
 	topics       []string
 	autoCommit   bool
+	maxRecords   int
+	maxBytes     int
+	maxWait      time.Duration
 	config       kafka.ConfigMap
 	consumer     *kafka.Consumer
 	mu           sync.RWMutex
@@ -43,6 +42,7 @@ func NewConfluentConsumerBuilder(name string) *ConsumerBuilder {
 		c: &confluentConsumer{
 			name:       name,
 			autoCommit: true,
+			maxRecords: defaultMaxPollRecords,
 			mu:         sync.RWMutex{},
 			maxOffsets: make(map[TopicPartition]kafka.Offset),
 			config:     kafka.ConfigMap{}, //default empty
@@ -68,6 +68,23 @@ func (cb *ConsumerBuilder) DisableAutoCommit() {
 	cb.c.autoCommit = false
 }
 
+//SetMaxPollRecords sets max messages returned by a Poll, default is 1.
+func (cb *ConsumerBuilder) SetMaxPollRecords(n int) {
+	cb.c.maxRecords = n
+}
+
+//SetMaxPollBytes sets size of message values after which Poll stops filling its batch.
+//Batch can exceed it by its last message. 0 means no limit.
+func (cb *ConsumerBuilder) SetMaxPollBytes(n int) {
+	cb.c.maxBytes = n
+}
+
+//SetMaxPollWait sets how long Poll waits to fill its batch after first message.
+//Default 0 fills it only with messages already fetched.
+func (cb *ConsumerBuilder) SetMaxPollWait(d time.Duration) {
+	cb.c.maxWait = d
+}
+
 func (cb *ConsumerBuilder) SetConfig(cfg map[string]interface{}) {
 	if cfg != nil {
 		for k, v := range cfg {
@@ -92,6 +109,10 @@ func (cb *ConsumerBuilder) Build() (KafkaConsumer, error) {
 		return nil, errors.New("please set the broker address")
 	}
 
+	if c.maxRecords < 1 || c.maxBytes < 0 || c.maxWait < 0 {
+		return nil, errors.New("please set positive poll limits")
+	}
+
 	hosts := strings.Join(c.brokers, ",")
 	defConfig := kafka.ConfigMap{
 		"bootstrap.servers":  hosts,
@@ -131,27 +152,77 @@ func (c *confluentConsumer) Setup() error {
 	return nil
 }
 
-//TODO make multiple calls to poll
-//Poll timeout is in ms
+//Poll waits upto timeout for a message, then fills a batch upto max poll records,
+//max poll bytes or max poll wait. Msgs read before an error are returned with it.
 func (c *confluentConsumer) Poll(timeout time.Duration) ([]Msg, error) {
 	if c.consumer == nil {
 		return nil, errors.New("attempt to poll on uninited consumer")
 	}
 
-	ev, err := c.consumer.ReadMessage(timeout)
+	return c.poll(c.consumer, timeout)
+}
+
+//poller is the part of kafka.Consumer used by Poll
+type poller interface {
+	ReadMessage(time.Duration) (*kafka.Message, error)
+	Poll(int) kafka.Event
+}
+
+func (c *confluentConsumer) poll(p poller, timeout time.Duration) ([]Msg, error) {
+	ev, err := p.ReadMessage(timeout)
 	if err != nil {
 		return nil, err
 	}
-	//ev is guaranteed to be non-nil now
-	msg, err := decode(ev)
-	if err != nil {
-		//what do we do with corrupted message, right now we are dropping them.
-		fmt.Printf("[ERROR] unable to decode message: %v, err: %v\n. Dropping.", ev, err)
-		c.Commit([]interface{}{ev.TopicPartition})
-		return nil, errors.Wrap(err, "unable to decode Msg")
+
+	msgs := []Msg{}
+	size := 0
+	end := time.Now().Add(c.maxWait)
+
+	for {
+		//ev is guaranteed to be non-nil now
+		msg, err := decode(ev)
+		if err != nil {
+			//what do we do with corrupted message, right now we are dropping them.
+			fmt.Printf("[ERROR] unable to decode message: %v, err: %v\n. Dropping.", ev, err)
+			c.Commit([]interface{}{ev.TopicPartition})
+		} else {
+			msgs = append(msgs, *msg)
+			size += len(msg.Data)
+		}
+
+		if len(msgs) >= c.maxRecords || (c.maxBytes > 0 && size >= c.maxBytes) {
+			return msgs, nil
+		}
+
+		ev, err = next(p, end)
+		if ev == nil {
+			return msgs, err
+		}
 	}
+}
+
+//next returns next message polled till end, nil when none came in time
+func next(p poller, end time.Time) (*kafka.Message, error) {
+	for {
+		wait := int(time.Until(end) / time.Millisecond)
+		if wait < 0 {
+			wait = 0
+		}
 
-	return []Msg{*msg}, nil
+		switch e := p.Poll(wait).(type) {
+		case *kafka.Message:
+			if e.TopicPartition.Error != nil {
+				return nil, e.TopicPartition.Error
+			}
+			return e, nil
+		case kafka.Error:
+			return nil, e
+		case nil:
+			return nil, nil
+		default:
+			// Ignore other event types
+		}
+	}
 }
 
 func (c *confluentConsumer) Commit(offsets []interface{}) error {
@@ -198,10 +269,13 @@ func (c *confluentConsumer) offsets(o []interface{}) ([]kafka.TopicPartition, er
 
 	for _, oi := range o {
 		switch t := oi.(type) {
+		case Offset:
+			topic := t.Topic
+			offsets = append(offsets, kafka.TopicPartition{Topic: &topic, Partition: t.Partition, Offset: kafka.Offset(t.Offset)})
 		case kafka.TopicPartition:
 			offsets = append(offsets, t)
 		default:
-			return nil, errors.New("offset(s) is not []kafka.TopicPartition")
+			return nil, errors.New("offset(s) is not []Offset or []kafka.TopicPartition")
 		}
 	}
 	return offsets, nil
@@ -260,6 +334,26 @@ func (c *confluentConsumer) syncPartition() {
 	}
 }
 
+//Should we check for data as JsonMessage??
+func decode(m *kafka.Message) (*Msg, error) {
+	msg := &Msg{}
+	if m.TopicPartition.Topic != nil {
+		msg.Topic = *m.TopicPartition.Topic
+	}
+	msg.Partition = m.TopicPartition.Partition
+	msg.Key = m.Key
+	msg.Data = m.Value
+	msg.Timestamp = m.Timestamp
+	for _, h := range m.Headers {
+		msg.Headers = append(msg.Headers, Header{Key: h.Key, Value: h.Value})
+	}
+	msg.offset = Offset{
+		TopicPartition: TopicPartition{Topic: msg.Topic, Partition: msg.Partition},
+		Offset:         int64(m.TopicPartition.Offset),
+	}
+	return msg, nil
+}
+
 func max(a, b kafka.Offset) kafka.Offset {
 	if a > b {
 		return a
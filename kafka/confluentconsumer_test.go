@@ -1,9 +1,14 @@
+//go:build cgo
+// +build cgo
+
 package kafka
 
 import (
+	"errors"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 //TODO fill this with 100% coverage of confluent and document cases tested
@@ -33,6 +38,16 @@ func TestConsumerBuilder(t *testing.T) {
 		assert.Nil(t, con)
 	})
 
+	t.Run("bad poll limits", func(t *testing.T) {
+		cb := NewConfluentConsumerBuilder("alpha")
+		cb.SetBroker([]string{"a:9092", "b:9092"})
+		cb.SetTopics([]string{"a", "b"})
+		cb.SetMaxPollRecords(0)
+		con, err := cb.Build()
+		assert.Error(t, err)
+		assert.Nil(t, con)
+	})
+
 	t.Run("all good", func(t *testing.T) {
 		cb := NewConfluentConsumerBuilder("alpha")
 		cb.SetBroker([]string{"a:9092", "b:9092"})
@@ -97,6 +112,11 @@ func TestConsumer(t *testing.T) {
 		assert.Equal(t, len(rawCon.maxOffsets), 2)
 		assert.Equal(t, rawCon.maxOffsets[tpB], kafka.Offset(1))
 
+		//commit of Msg offset works
+		assert.NoError(t, rawCon.Commit([]interface{}{Offset{TopicPartition: tpB, Offset: 4}}))
+		assert.Equal(t, len(rawCon.maxOffsets), 2)
+		assert.Equal(t, rawCon.maxOffsets[tpB], kafka.Offset(5))
+
 	})
 
 	t.Run("client close", func(t *testing.T) {
@@ -128,3 +148,110 @@ func TestConsumer(t *testing.T) {
 	})
 
 }
+
+//testPoller returns msgs of given sizes, then times out or fails
+type testPoller struct {
+	sizes []int
+	fail  bool
+	i     int
+}
+
+func (p *testPoller) ReadMessage(time.Duration) (*kafka.Message, error) {
+	if ev, ok := p.Poll(0).(*kafka.Message); ok {
+		return ev, nil
+	}
+	return nil, errors.New("timed out")
+}
+
+func (p *testPoller) Poll(int) kafka.Event {
+	if p.i == len(p.sizes) {
+		if p.fail {
+			return kafka.Error{}
+		}
+		return nil
+	}
+
+	topic := "a"
+	m := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(p.i)},
+		Value:          make([]byte, p.sizes[p.i]),
+	}
+	p.i++
+	return m
+}
+
+func TestConsumerPoll(t *testing.T) {
+
+	t.Run("one record by default", func(t *testing.T) {
+		c := NewConfluentConsumerBuilder("alpha").c
+		msgs, err := c.poll(&testPoller{sizes: []int{1, 1, 1}}, time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(msgs))
+	})
+
+	t.Run("max records", func(t *testing.T) {
+		cb := NewConfluentConsumerBuilder("alpha")
+		cb.SetMaxPollRecords(2)
+		msgs, err := cb.c.poll(&testPoller{sizes: []int{1, 1, 1}}, time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(msgs))
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		cb := NewConfluentConsumerBuilder("alpha")
+		cb.SetMaxPollRecords(10)
+		cb.SetMaxPollBytes(5)
+		msgs, err := cb.c.poll(&testPoller{sizes: []int{2, 2, 2, 2}}, time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(msgs))
+	})
+
+	t.Run("partial batch on timeout", func(t *testing.T) {
+		cb := NewConfluentConsumerBuilder("alpha")
+		cb.SetMaxPollRecords(10)
+		msgs, err := cb.c.poll(&testPoller{sizes: []int{1, 1}}, time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(msgs))
+		assert.Equal(t, int64(1), msgs[1].Offset().Offset)
+	})
+
+	t.Run("no message", func(t *testing.T) {
+		c := NewConfluentConsumerBuilder("alpha").c
+		msgs, err := c.poll(&testPoller{}, time.Second)
+		assert.Error(t, err)
+		assert.Nil(t, msgs)
+	})
+
+	t.Run("error after messages", func(t *testing.T) {
+		cb := NewConfluentConsumerBuilder("alpha")
+		cb.SetMaxPollRecords(10)
+		msgs, err := cb.c.poll(&testPoller{sizes: []int{1}, fail: true}, time.Second)
+		assert.Error(t, err)
+		assert.Equal(t, 1, len(msgs))
+	})
+}
+
+func TestDecode(t *testing.T) {
+	topic := "a"
+	ts := time.Unix(1500000000, 0)
+	msg, err := decode(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 7},
+		Key:            []byte("key"),
+		Value:          []byte("value"),
+		Timestamp:      ts,
+		Headers:        []kafka.Header{{Key: "trace", Value: []byte("1")}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", msg.Topic)
+	assert.Equal(t, int32(2), msg.Partition)
+	assert.Equal(t, []byte("key"), msg.Key)
+	assert.Equal(t, []byte("value"), msg.Data)
+	assert.Equal(t, ts, msg.Timestamp)
+	assert.Equal(t, Offset{TopicPartition: TopicPartition{Topic: "a", Partition: 2}, Offset: 7}, msg.Offset())
+
+	v, ok := msg.Header("trace")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+	_, ok = msg.Header("missing")
+	assert.False(t, ok)
+}
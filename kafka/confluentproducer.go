@@ -0,0 +1,202 @@
+//go:build cgo
+// +build cgo
+
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	flushPeriod = 10 * time.Second
+)
+
+//ErrUndelivered is reported for msgs not delivered before producer is closed.
+var ErrUndelivered = errors.New("message not delivered before close")
+
+type confluentProducer struct {
+	topic string
+	p     *kafka.Producer
+	done  chan struct{}
+	flush time.Duration
+
+	mu      sync.Mutex
+	pending map[*confluentMeta]struct{} //async msgs waiting for delivery report
+}
+
+//confluentMeta is Opaque of confluent message, async msgs are reported with it.
+type confluentMeta struct {
+	msg    *ProducerMsg
+	report DeliveryReport
+}
+
+//NewConfluentProducer creates producer of topic, cfg is librdkafka configuration.
+func NewConfluentProducer(topic string, hosts []string, cfg map[string]interface{}) (KafkaProducer, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("please set the broker address")
+	}
+
+	config := kafka.ConfigMap{}
+	for k, v := range cfg {
+		config[k] = v
+	}
+	config["bootstrap.servers"] = strings.Join(hosts, ",")
+
+	kp, err := kafka.NewProducer(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &confluentProducer{
+		topic:   topic,
+		p:       kp,
+		done:    make(chan struct{}),
+		flush:   flushPeriod,
+		pending: map[*confluentMeta]struct{}{},
+	}
+	go p.reports()
+	return p, nil
+}
+
+func (p *confluentProducer) Write(msgs []json.RawMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	pms := []*ProducerMsg{}
+	for _, m := range msgs {
+		pms = append(pms, &ProducerMsg{Value: m})
+	}
+	return p.Send(pms)
+}
+
+func (p *confluentProducer) Send(msgs []*ProducerMsg) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	deliveries := make(chan kafka.Event, len(msgs))
+	produced := 0
+	var err error
+
+	for _, m := range msgs {
+		km, merr := p.message(m, nil)
+		if merr == nil {
+			merr = p.p.Produce(km, deliveries)
+		}
+		if merr != nil {
+			err = merr
+			break
+		}
+		produced++
+	}
+
+	//wait for msgs already produced even on error, so that deliveries is not left behind
+	for i := 0; i < produced; i++ {
+		if m, ok := (<-deliveries).(*kafka.Message); ok && m.TopicPartition.Error != nil && err == nil {
+			err = m.TopicPartition.Error
+		}
+	}
+	return err
+}
+
+func (p *confluentProducer) SendAsync(m *ProducerMsg, report DeliveryReport) {
+	km, err := p.message(m, report)
+	if err == nil {
+		meta := km.Opaque.(*confluentMeta)
+		p.track(meta)
+		if err = p.p.Produce(km, nil); err != nil {
+			p.untrack(meta)
+		}
+	}
+	if err != nil && report != nil {
+		report(m, Offset{}, err)
+	}
+}
+
+//Close flushes msgs and reports ErrUndelivered to async msgs still pending after flush.
+func (p *confluentProducer) Close() error {
+	n := p.p.Flush(int(p.flush / time.Millisecond))
+	p.p.Close()
+	<-p.done
+
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = map[*confluentMeta]struct{}{}
+	p.mu.Unlock()
+
+	for meta := range pending {
+		if meta.report != nil {
+			meta.report(meta.msg, Offset{}, ErrUndelivered)
+		}
+	}
+
+	if n > 0 {
+		return errors.Wrapf(ErrUndelivered, "%d messages", n)
+	}
+	return nil
+}
+
+//track marks async msg pending till its delivery is reported.
+func (p *confluentProducer) track(meta *confluentMeta) {
+	p.mu.Lock()
+	p.pending[meta] = struct{}{}
+	p.mu.Unlock()
+}
+
+//untrack removes async msg from pending, false if it was not pending.
+func (p *confluentProducer) untrack(meta *confluentMeta) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.pending[meta]
+	delete(p.pending, meta)
+	return ok
+}
+
+func (p *confluentProducer) message(m *ProducerMsg, report DeliveryReport) (*kafka.Message, error) {
+	topic := m.Topic
+	if topic == "" {
+		topic = p.topic
+	}
+	if topic == "" {
+		return nil, errors.New("please set message topic")
+	}
+
+	km := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            m.Key,
+		Value:          m.Value,
+		Opaque:         &confluentMeta{msg: m, report: report},
+	}
+	if m.Partition != nil {
+		km.TopicPartition.Partition = *m.Partition
+	}
+	for _, h := range m.Headers {
+		km.Headers = append(km.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+	}
+	return km, nil
+}
+
+//reports calls DeliveryReport of async msgs till producer is closed.
+func (p *confluentProducer) reports() {
+	defer close(p.done)
+
+	for ev := range p.p.Events() {
+		switch e := ev.(type) {
+		case *kafka.Message:
+			meta, ok := e.Opaque.(*confluentMeta)
+			if !ok || !p.untrack(meta) || meta.report == nil {
+				continue
+			}
+			msg, _ := decode(e)
+			meta.report(meta.msg, msg.Offset(), e.TopicPartition.Error)
+		case kafka.Error:
+			fmt.Printf("[ERROR] producer error: %v\n", e)
+		}
+	}
+}
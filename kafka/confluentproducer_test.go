@@ -0,0 +1,61 @@
+//go:build cgo
+// +build cgo
+
+package kafka
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestConfluentProducer(t *testing.T) {
+
+	t.Run("broker not set", func(t *testing.T) {
+		p, err := NewConfluentProducer("test", []string{}, nil)
+		assert.Error(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("topic not set", func(t *testing.T) {
+		p, err := NewConfluentProducer("", []string{"a:9092"}, nil)
+		assert.NoError(t, err)
+
+		assert.Error(t, p.Send([]*ProducerMsg{{Value: []byte("a")}}))
+
+		var reported error
+		p.SendAsync(&ProducerMsg{Value: []byte("a")}, func(m *ProducerMsg, o Offset, err error) {
+			reported = err
+		})
+		assert.Error(t, reported)
+		assert.NoError(t, p.Close())
+	})
+
+	t.Run("undelivered on close", func(t *testing.T) {
+		p, err := NewConfluentProducer("test", []string{"localhost:1"}, nil)
+		assert.NoError(t, err)
+		p.(*confluentProducer).flush = 100 * time.Millisecond
+
+		var reported error
+		p.SendAsync(&ProducerMsg{Value: []byte("a")}, func(m *ProducerMsg, o Offset, err error) {
+			reported = err
+		})
+
+		err = p.Close()
+		assert.Equal(t, ErrUndelivered, errors.Cause(err))
+		assert.Contains(t, err.Error(), "1 messages")
+		assert.Equal(t, ErrUndelivered, reported)
+	})
+
+	t.Run("partition override", func(t *testing.T) {
+		p := &confluentProducer{topic: "test"}
+		two := int32(2)
+		km, err := p.message(&ProducerMsg{Key: []byte("k"), Partition: &two, Headers: []Header{{Key: "h", Value: []byte("v")}}}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "test", *km.TopicPartition.Topic)
+		assert.Equal(t, two, km.TopicPartition.Partition)
+		assert.Equal(t, []byte("k"), km.Key)
+		assert.Equal(t, "h", km.Headers[0].Key)
+	})
+}
@@ -4,9 +4,16 @@ import (
 	"time"
 )
 
+const (
+	commitPeriod = 5 * time.Second
+	drainPeriod  = 2 * time.Second
+
+	defaultMaxPollRecords = 1
+)
+
 type KafkaConsumer interface {
 	Setup() error
 	Poll(time.Duration) ([]Msg, error)
-	Commit([]interface{}) error //pass commit handle e.g kafka.TopicPartition for confluent one
+	Commit([]interface{}) error //pass commit handle Msg.Offset(), confluent one also takes kafka.TopicPartition
 	Close() error
 }
@@ -1,22 +1,46 @@
 package kafka
 
 import (
-	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"time"
 )
 
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+//Offset of a message in its partition, it is the commit handle of Msg.
+type Offset struct {
+	TopicPartition
+	Offset int64
+}
+
+type Header struct {
+	Key   string
+	Value []byte
+}
+
 type Msg struct {
-	Data   []byte
-	offset interface{}
+	Topic     string
+	Partition int32
+	Key       []byte
+	Data      []byte
+	Headers   []Header
+	Timestamp time.Time
+	offset    Offset
 }
 
-func (m *Msg) Offset() interface{} {
+//Offset is passed to KafkaConsumer.Commit once Msg is processed.
+func (m *Msg) Offset() Offset {
 	return m.offset
 }
 
-//Should we check for data as JsonMessage??
-func decode(m *kafka.Message) (*Msg, error) {
-	msg := &Msg{}
-	msg.Data = m.Value
-	msg.offset = m.TopicPartition
-	return msg, nil
+//Header returns value of first header with key.
+func (m *Msg) Header(key string) ([]byte, bool) {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return h.Value, true
+		}
+	}
+	return nil, false
 }
@@ -5,6 +5,50 @@ import (
 )
 
 type KafkaProducer interface {
-	Write([]json.RawMessage) error
-	Close() error
+	Write([]json.RawMessage) error //writes values to producer topic
+	Send([]*ProducerMsg) error     //writes msgs and waits for their delivery
+	SendAsync(*ProducerMsg, DeliveryReport)
+	Close() error //waits for delivery of async msgs, SendAsync must not be called after it
 }
+
+//ProducerMsg is a message to produce.
+type ProducerMsg struct {
+	Topic     string //producer topic is used when empty
+	Key       []byte //partitioner picks partition by key
+	Value     []byte
+	Headers   []Header
+	Partition *int32 //overrides partitioner when set
+}
+
+//DeliveryReport is called with offset of an async msg once it is written, or with its error.
+type DeliveryReport func(m *ProducerMsg, o Offset, err error)
+
+//Acks is number of acks a producer waits for before a write succeeds.
+type Acks int16
+
+const (
+	AcksNone   Acks = 0  //no ack, fastest but writes may be lost
+	AcksLeader Acks = 1  //leader wrote it
+	AcksAll    Acks = -1 //all in-sync replicas wrote it
+)
+
+//Compression is codec of message batches.
+type Compression int8
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionSnappy
+	CompressionLZ4
+	CompressionZstd //needs kafka 2.1
+)
+
+//Partitioner picks partition of a msg without Partition.
+type Partitioner int
+
+const (
+	PartitionerHash       Partitioner = iota //fnv-1a of key, random when key is nil
+	PartitionerMurmur2                       //murmur2 of key as java client, round robin when key is nil
+	PartitionerRoundRobin                    //ignores key
+	PartitionerManual                        //msgs without Partition go to partition 0
+)
@@ -0,0 +1,406 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+//ErrPollTimedOut is returned by Poll of sarama consumer when no message came in time.
+var ErrPollTimedOut = errors.New("poll timed out")
+
+type saramaConsumer struct {
+	name         string
+	brokers      []string
+	topics       []string
+	version      string
+	autoCommit   bool
+	maxRecords   int
+	maxBytes     int
+	maxWait      time.Duration
+	config       *sarama.Config
+	group        sarama.ConsumerGroup
+	msgs         chan *sarama.ConsumerMessage
+	ctx          context.Context
+	cancel       context.CancelFunc
+	consumeDone  chan struct{}
+	mu           sync.RWMutex
+	session      sarama.ConsumerGroupSession
+	maxOffsets   map[TopicPartition]int64
+	commiterDone chan struct{}
+}
+
+type SaramaConsumerBuilder struct {
+	c *saramaConsumer
+}
+
+//NewSaramaConsumerBuilder creates builder of consumer of group name, it needs no librdkafka.
+func NewSaramaConsumerBuilder(name string) *SaramaConsumerBuilder {
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_11_0_0 // Consumer groups need 0.10.2, headers need 0.11
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SaramaConsumerBuilder{
+		c: &saramaConsumer{
+			name:       name,
+			autoCommit: true,
+			maxRecords: defaultMaxPollRecords,
+			config:     config,
+			msgs:       make(chan *sarama.ConsumerMessage),
+			ctx:        ctx,
+			cancel:     cancel,
+			mu:         sync.RWMutex{},
+			maxOffsets: make(map[TopicPartition]int64),
+		},
+	}
+}
+
+func (cb *SaramaConsumerBuilder) SetBroker(hosts []string) {
+	cb.c.brokers = hosts
+}
+
+func (cb *SaramaConsumerBuilder) SetTopics(topics []string) {
+	saneTopics := []string{}
+	for _, t := range topics {
+		if t != "" {
+			saneTopics = append(saneTopics, t)
+		}
+	}
+	cb.c.topics = saneTopics
+}
+
+func (cb *SaramaConsumerBuilder) DisableAutoCommit() {
+	cb.c.autoCommit = false
+}
+
+//SetMaxPollRecords sets max messages returned by a Poll, default is 1.
+func (cb *SaramaConsumerBuilder) SetMaxPollRecords(n int) {
+	cb.c.maxRecords = n
+}
+
+//SetMaxPollBytes sets size of message values after which Poll stops filling its batch.
+//Batch can exceed it by its last message. 0 means no limit.
+func (cb *SaramaConsumerBuilder) SetMaxPollBytes(n int) {
+	cb.c.maxBytes = n
+}
+
+//SetMaxPollWait sets how long Poll waits to fill its batch after first message.
+//Default 0 fills it only with messages already fetched.
+func (cb *SaramaConsumerBuilder) SetMaxPollWait(d time.Duration) {
+	cb.c.maxWait = d
+}
+
+//SetVersion sets kafka version of brokers e.g 2.1.0, default is 0.11.0.
+func (cb *SaramaConsumerBuilder) SetVersion(version string) {
+	cb.c.version = version
+}
+
+func (cb *SaramaConsumerBuilder) SetTLS(cfg *tls.Config) {
+	cb.c.config.Net.TLS.Enable = true
+	cb.c.config.Net.TLS.Config = cfg
+}
+
+//SetSASL enables SASL/PLAIN authentication.
+func (cb *SaramaConsumerBuilder) SetSASL(user, password string) {
+	cb.c.config.Net.SASL.Enable = true
+	cb.c.config.Net.SASL.User = user
+	cb.c.config.Net.SASL.Password = password
+}
+
+//Build errs when config is invalid or cluster is not reachable.
+func (cb *SaramaConsumerBuilder) Build() (KafkaConsumer, error) {
+
+	c := cb.c
+
+	if c.name == "" {
+		return nil, errors.New("please set consumer name")
+	}
+
+	if len(c.topics) == 0 {
+		return nil, errors.New("please set consumer topic(s)")
+	}
+
+	if len(c.brokers) == 0 {
+		return nil, errors.New("please set the broker address")
+	}
+
+	if c.maxRecords < 1 || c.maxBytes < 0 || c.maxWait < 0 {
+		return nil, errors.New("please set positive poll limits")
+	}
+
+	if c.version != "" {
+		v, err := sarama.ParseKafkaVersion(c.version)
+		if err != nil {
+			return nil, err
+		}
+		c.config.Version = v
+	}
+
+	group, err := sarama.NewConsumerGroup(c.brokers, c.name, c.config)
+	if err != nil {
+		return nil, err
+	}
+	c.group = group
+
+	return c, nil
+}
+
+//TODO call under sync.Once
+func (c *saramaConsumer) Setup() error {
+	if c.group == nil {
+		return errors.New("attempt to Setup uninited consumer")
+	}
+
+	c.consumeDone = make(chan struct{})
+	go c.consume()
+
+	if !c.autoCommit {
+		c.commiterDone = make(chan struct{})
+		go c.commiter()
+	}
+
+	return nil
+}
+
+//Poll waits upto timeout for a message, then fills a batch upto max poll records,
+//max poll bytes or max poll wait.
+func (c *saramaConsumer) Poll(timeout time.Duration) ([]Msg, error) {
+	if c.group == nil {
+		return nil, errors.New("attempt to poll on uninited consumer")
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var m *sarama.ConsumerMessage
+	select {
+	case m = <-c.msgs:
+	case <-timer.C:
+		return nil, ErrPollTimedOut
+	}
+
+	msgs := []Msg{}
+	size := 0
+	end := time.NewTimer(c.maxWait)
+	defer end.Stop()
+
+	for {
+		msg := decodeSarama(m)
+		msgs = append(msgs, *msg)
+		size += len(msg.Data)
+
+		if c.autoCommit {
+			c.mark(msg.Offset())
+		}
+
+		if len(msgs) >= c.maxRecords || (c.maxBytes > 0 && size >= c.maxBytes) {
+			return msgs, nil
+		}
+
+		//take a message already fetched before waiting for one
+		select {
+		case m = <-c.msgs:
+			continue
+		default:
+		}
+
+		select {
+		case m = <-c.msgs:
+		case <-end.C:
+			return msgs, nil
+		}
+	}
+}
+
+func (c *saramaConsumer) Commit(offsets []interface{}) error {
+	if c.group == nil {
+		return errors.New("attempt to Commit on uninited consumer")
+	}
+
+	if !c.autoCommit {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for _, oi := range offsets {
+			of, ok := oi.(Offset)
+			if !ok {
+				return errors.New("offset(s) is not []Offset")
+			}
+			if of.Topic == "" {
+				return errors.New(fmt.Sprintf("topic missing for offset: %v", of))
+			}
+			old := c.maxOffsets[of.TopicPartition]
+			if of.Offset+1 > old {
+				c.maxOffsets[of.TopicPartition] = of.Offset + 1
+			}
+		}
+	}
+	return nil
+}
+
+//TODO call under sync.Once
+func (c *saramaConsumer) Close() error {
+	if c.group == nil {
+		return errors.New("attempt to Close uninited consumer")
+	}
+
+	if c.commiterDone != nil {
+		close(c.commiterDone)
+		time.Sleep(drainPeriod)
+	}
+
+	c.cancel()
+	err := c.group.Close()
+	if c.consumeDone != nil {
+		<-c.consumeDone
+	}
+	return err
+}
+
+//consume joins group till consumer is closed, a session ends on every rebalance.
+func (c *saramaConsumer) consume() {
+	defer close(c.consumeDone)
+
+	for c.ctx.Err() == nil {
+		if err := c.group.Consume(c.ctx, c.topics, groupHandler{c: c}); err != nil {
+			fmt.Printf("error in consuming: %v\n.", err)
+			if err == sarama.ErrClosedConsumerGroup {
+				return
+			}
+			time.Sleep(drainPeriod)
+		}
+	}
+}
+
+//groupHandler is sarama.ConsumerGroupHandler of consumer.
+type groupHandler struct {
+	c *saramaConsumer
+}
+
+//Setup is called by sarama when a session starts.
+func (h groupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	c := h.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.session = session
+	c.syncPartition()
+	return nil
+}
+
+//Cleanup is called by sarama when a session ends, before its offsets are committed.
+func (h groupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	c := h.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.markOffsets()
+	c.session = nil
+	return nil
+}
+
+//ConsumeClaim hands messages of a partition to Poll till session ends.
+func (h groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case m, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			select {
+			case h.c.msgs <- m:
+			case <-session.Context().Done():
+				return nil
+			}
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+//mark marks offset as consumed in current session, sarama commits it periodically.
+func (c *saramaConsumer) mark(o Offset) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.session != nil {
+		c.session.MarkOffset(o.Topic, o.Partition, o.Offset+1, "")
+	}
+}
+
+func (c *saramaConsumer) commiter() {
+	timer := time.NewTicker(commitPeriod)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.commiterDone:
+			c.commitOffsets()
+			return
+		case <-timer.C:
+			c.commitOffsets()
+		}
+	}
+}
+
+func (c *saramaConsumer) commitOffsets() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.syncPartition()
+	c.markOffsets()
+}
+
+//markOffsets marks max offsets in current session, caller holds mu.
+func (c *saramaConsumer) markOffsets() {
+	if c.session == nil {
+		return
+	}
+	for tp, o := range c.maxOffsets {
+		c.session.MarkOffset(tp.Topic, tp.Partition, o, "")
+	}
+}
+
+//syncPartition drops offsets of partitions not claimed by current session, caller holds mu.
+func (c *saramaConsumer) syncPartition() {
+	if c.session == nil {
+		return
+	}
+
+	claimed := make(map[TopicPartition]struct{})
+	for topic, partitions := range c.session.Claims() {
+		for _, p := range partitions {
+			claimed[TopicPartition{Topic: topic, Partition: p}] = struct{}{}
+		}
+	}
+
+	for k := range c.maxOffsets {
+		if _, ok := claimed[k]; !ok {
+			delete(c.maxOffsets, k)
+		}
+	}
+}
+
+func decodeSarama(m *sarama.ConsumerMessage) *Msg {
+	msg := &Msg{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Key:       m.Key,
+		Data:      m.Value,
+		Timestamp: m.Timestamp,
+		offset: Offset{
+			TopicPartition: TopicPartition{Topic: m.Topic, Partition: m.Partition},
+			Offset:         m.Offset,
+		},
+	}
+	for _, h := range m.Headers {
+		if h != nil {
+			msg.Headers = append(msg.Headers, Header{Key: string(h.Key), Value: h.Value})
+		}
+	}
+	return msg
+}
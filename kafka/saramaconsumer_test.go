@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSaramaConsumerBuilder(t *testing.T) {
+
+	t.Run("name not set", func(t *testing.T) {
+		cb := NewSaramaConsumerBuilder("")
+		con, err := cb.Build()
+		assert.Error(t, err)
+		assert.Nil(t, con)
+	})
+
+	t.Run("broker not set", func(t *testing.T) {
+		cb := NewSaramaConsumerBuilder("alpha")
+		cb.SetTopics([]string{"a", "b"})
+		con, err := cb.Build()
+		assert.Error(t, err)
+		assert.Nil(t, con)
+	})
+
+	t.Run("topics not set", func(t *testing.T) {
+		cb := NewSaramaConsumerBuilder("alpha")
+		cb.SetBroker([]string{"a:9092", "b:9092"})
+		con, err := cb.Build()
+		assert.Error(t, err)
+		assert.Nil(t, con)
+	})
+
+	t.Run("bad version", func(t *testing.T) {
+		cb := NewSaramaConsumerBuilder("alpha")
+		cb.SetBroker([]string{"a:9092"})
+		cb.SetTopics([]string{"a"})
+		cb.SetVersion("lala")
+		con, err := cb.Build()
+		assert.Error(t, err)
+		assert.Nil(t, con)
+	})
+
+	t.Run("all good", func(t *testing.T) {
+		b := sarama.NewMockBroker(t, 1)
+		defer b.Close()
+		b.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(t).
+				SetBroker(b.Addr(), b.BrokerID()).
+				SetLeader("a", 0, b.BrokerID()),
+		})
+
+		cb := NewSaramaConsumerBuilder("alpha")
+		cb.SetBroker([]string{b.Addr()})
+		cb.SetTopics([]string{"a"})
+		con, err := cb.Build()
+		assert.NoError(t, err)
+		assert.NotNil(t, con)
+		assert.NoError(t, con.Close())
+	})
+}
+
+//testSaramaConsumer is a consumer without group, for Poll and Commit
+func testSaramaConsumer() *saramaConsumer {
+	c := NewSaramaConsumerBuilder("alpha").c
+	c.group = &testGroup{}
+	return c
+}
+
+type testGroup struct {
+	sarama.ConsumerGroup
+}
+
+func TestSaramaConsumerCommit(t *testing.T) {
+	c := testSaramaConsumer()
+	c.autoCommit = false
+	tp := TopicPartition{Topic: "a", Partition: 0}
+	tpB := TopicPartition{Topic: "b", Partition: 0}
+
+	//bad partition commit errs
+	assert.Error(t, c.Commit([]interface{}{1}))
+	assert.Error(t, c.Commit([]interface{}{Offset{}}))
+	assert.Equal(t, len(c.maxOffsets), 0)
+
+	assert.NoError(t, c.Commit([]interface{}{Offset{TopicPartition: tp, Offset: 0}}))
+	assert.Equal(t, c.maxOffsets[tp], int64(1))
+
+	assert.NoError(t, c.Commit([]interface{}{Offset{TopicPartition: tp, Offset: 1}}))
+	assert.Equal(t, c.maxOffsets[tp], int64(2))
+
+	//out of order commit is NO-OP
+	assert.NoError(t, c.Commit([]interface{}{Offset{TopicPartition: tp, Offset: 0}}))
+	assert.Equal(t, c.maxOffsets[tp], int64(2))
+
+	//jump commit is allowed
+	assert.NoError(t, c.Commit([]interface{}{Offset{TopicPartition: tp, Offset: 10}}))
+	assert.Equal(t, c.maxOffsets[tp], int64(11))
+
+	//commit to 2nd topic works
+	assert.NoError(t, c.Commit([]interface{}{Offset{TopicPartition: tpB, Offset: 0}}))
+	assert.Equal(t, len(c.maxOffsets), 2)
+	assert.Equal(t, c.maxOffsets[tpB], int64(1))
+}
+
+func testSaramaMsgs(c *saramaConsumer, sizes ...int) {
+	go func() {
+		for i, n := range sizes {
+			c.msgs <- &sarama.ConsumerMessage{Topic: "a", Offset: int64(i), Value: make([]byte, n)}
+		}
+	}()
+}
+
+func TestSaramaConsumerPoll(t *testing.T) {
+
+	t.Run("no message", func(t *testing.T) {
+		c := testSaramaConsumer()
+		msgs, err := c.Poll(10 * time.Millisecond)
+		assert.Equal(t, ErrPollTimedOut, err)
+		assert.Nil(t, msgs)
+	})
+
+	t.Run("max records", func(t *testing.T) {
+		c := testSaramaConsumer()
+		c.maxRecords = 2
+		c.maxWait = time.Second
+		testSaramaMsgs(c, 1, 1, 1)
+		msgs, err := c.Poll(time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(msgs))
+		assert.Equal(t, int64(1), msgs[1].Offset().Offset)
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		c := testSaramaConsumer()
+		c.maxRecords = 10
+		c.maxBytes = 5
+		c.maxWait = time.Second
+		testSaramaMsgs(c, 2, 2, 2, 2)
+		msgs, err := c.Poll(time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(msgs))
+	})
+
+	t.Run("partial batch on max wait", func(t *testing.T) {
+		c := testSaramaConsumer()
+		c.maxRecords = 10
+		c.maxWait = 10 * time.Millisecond
+		testSaramaMsgs(c, 1, 1)
+		msgs, err := c.Poll(time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(msgs))
+	})
+}
+
+func TestDecodeSarama(t *testing.T) {
+	ts := time.Unix(1500000000, 0)
+	msg := decodeSarama(&sarama.ConsumerMessage{
+		Topic:     "a",
+		Partition: 2,
+		Offset:    7,
+		Key:       []byte("key"),
+		Value:     []byte("value"),
+		Timestamp: ts,
+		Headers:   []*sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("1")}},
+	})
+	assert.Equal(t, "a", msg.Topic)
+	assert.Equal(t, int32(2), msg.Partition)
+	assert.Equal(t, []byte("key"), msg.Key)
+	assert.Equal(t, []byte("value"), msg.Data)
+	assert.Equal(t, ts, msg.Timestamp)
+	assert.Equal(t, []Header{{Key: "trace", Value: []byte("1")}}, msg.Headers)
+	assert.Equal(t, Offset{TopicPartition: TopicPartition{Topic: "a", Partition: 2}, Offset: 7}, msg.Offset())
+}
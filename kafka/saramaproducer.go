@@ -1,43 +1,360 @@
 package kafka
 
 import (
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"time"
+)
+
+var (
+	compressionCodecs = map[Compression]sarama.CompressionCodec{
+		CompressionNone:   sarama.CompressionNone,
+		CompressionGzip:   sarama.CompressionGZIP,
+		CompressionSnappy: sarama.CompressionSnappy,
+		CompressionLZ4:    sarama.CompressionLZ4,
+		CompressionZstd:   sarama.CompressionZSTD,
+	}
+
+	partitioners = map[Partitioner]sarama.PartitionerConstructor{
+		PartitionerHash:       sarama.NewHashPartitioner,
+		PartitionerMurmur2:    newMurmur2Partitioner,
+		PartitionerRoundRobin: sarama.NewRoundRobinPartitioner,
+		PartitionerManual:     sarama.NewManualPartitioner,
+	}
 )
 
 type saramaProducer struct {
-	topic string
-	p     sarama.SyncProducer
+	topic  string
+	client sarama.Client
+	p      sarama.SyncProducer
+	ap     sarama.AsyncProducer
+	done   chan struct{}
 }
 
-//NewSaramaProducer errs when cluster is not reachable.
-func NewSaramaProducer(topic string, hosts []string) (KafkaProducer, error) {
+//saramaMeta is Metadata of sarama message, async msgs are reported with it.
+type saramaMeta struct {
+	msg    *ProducerMsg
+	report DeliveryReport
+}
+
+type SaramaProducerBuilder struct {
+	topic       string
+	brokers     []string
+	version     string
+	partitioner Partitioner
+	config      *sarama.Config
+}
+
+//NewSaramaProducerBuilder creates builder of producer of topic.
+//Producer waits for all in-sync replicas and retries upto 5 times by default.
+func NewSaramaProducerBuilder(topic string) *SaramaProducerBuilder {
 	config := sarama.NewConfig()
+	config.Version = sarama.V0_11_0_0                // Headers need 0.11 message format
 	config.Producer.RequiredAcks = sarama.WaitForAll // Wait for all in-sync replicas to ack the message
 	config.Producer.Retry.Max = 5                    // Retry up to 5 times to produce the message
 	config.Producer.Return.Successes = true
 
-	kp, err := sarama.NewSyncProducer(hosts, config)
+	return &SaramaProducerBuilder{topic: topic, config: config}
+}
+
+func (pb *SaramaProducerBuilder) SetBroker(hosts []string) {
+	pb.brokers = hosts
+}
+
+//SetVersion sets kafka version of brokers e.g 2.1.0, default is 0.11.0.
+func (pb *SaramaProducerBuilder) SetVersion(version string) {
+	pb.version = version
+}
+
+func (pb *SaramaProducerBuilder) SetAcks(acks Acks) {
+	pb.config.Producer.RequiredAcks = sarama.RequiredAcks(acks)
+}
+
+//EnableIdempotence writes every msg exactly once per partition, it needs AcksAll.
+func (pb *SaramaProducerBuilder) EnableIdempotence() {
+	pb.config.Producer.Idempotent = true
+	pb.config.Producer.RequiredAcks = sarama.WaitForAll
+	pb.config.Net.MaxOpenRequests = 1
+}
+
+func (pb *SaramaProducerBuilder) SetCompression(c Compression) {
+	pb.config.Producer.Compression = compressionCodecs[c]
+}
+
+//SetLinger sets how long msgs are batched before they are sent.
+func (pb *SaramaProducerBuilder) SetLinger(d time.Duration) {
+	pb.config.Producer.Flush.Frequency = d
+}
+
+//SetBatchSize sets bytes of batched msgs after which they are sent before linger.
+func (pb *SaramaProducerBuilder) SetBatchSize(bytes int) {
+	pb.config.Producer.Flush.Bytes = bytes
+}
+
+func (pb *SaramaProducerBuilder) SetMaxMessageBytes(bytes int) {
+	pb.config.Producer.MaxMessageBytes = bytes
+}
+
+func (pb *SaramaProducerBuilder) SetPartitioner(p Partitioner) {
+	pb.partitioner = p
+}
+
+func (pb *SaramaProducerBuilder) SetTLS(cfg *tls.Config) {
+	pb.config.Net.TLS.Enable = true
+	pb.config.Net.TLS.Config = cfg
+}
+
+//SetSASL enables SASL/PLAIN authentication.
+func (pb *SaramaProducerBuilder) SetSASL(user, password string) {
+	pb.config.Net.SASL.Enable = true
+	pb.config.Net.SASL.User = user
+	pb.config.Net.SASL.Password = password
+}
+
+//Build errs when config is invalid or cluster is not reachable.
+func (pb *SaramaProducerBuilder) Build() (KafkaProducer, error) {
+	if len(pb.brokers) == 0 {
+		return nil, errors.New("please set the broker address")
+	}
+
+	if pb.version != "" {
+		v, err := sarama.ParseKafkaVersion(pb.version)
+		if err != nil {
+			return nil, err
+		}
+		pb.config.Version = v
+	}
+
+	partitioner, ok := partitioners[pb.partitioner]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown partitioner: %v", pb.partitioner))
+	}
+	pb.config.Producer.Partitioner = overridePartitions(partitioner)
+
+	if err := pb.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newSaramaProducer(pb.topic, pb.brokers, pb.config)
+}
+
+//NewSaramaProducer errs when cluster is not reachable.
+func NewSaramaProducer(topic string, hosts []string) (KafkaProducer, error) {
+	pb := NewSaramaProducerBuilder(topic)
+	pb.SetBroker(hosts)
+	return pb.Build()
+}
+
+func newSaramaProducer(topic string, hosts []string, config *sarama.Config) (KafkaProducer, error) {
+	client, err := sarama.NewClient(hosts, config)
+	if err != nil {
+		return nil, err
+	}
+
+	kp, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	ap, err := sarama.NewAsyncProducerFromClient(client)
 	if err != nil {
+		kp.Close()
+		client.Close()
 		return nil, err
 	}
-	return &saramaProducer{topic: topic, p: kp}, nil
+
+	p := &saramaProducer{topic: topic, client: client, p: kp, ap: ap, done: make(chan struct{})}
+	go p.reports()
+	return p, nil
 }
 
 func (p *saramaProducer) Write(msgs []json.RawMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	pms := []*ProducerMsg{}
+	for _, m := range msgs {
+		pms = append(pms, &ProducerMsg{Value: m})
+	}
+	return p.Send(pms)
+}
+
+func (p *saramaProducer) Send(msgs []*ProducerMsg) error {
 	if len(msgs) == 0 {
 		return nil
 	}
 	saramaMsgs := []*sarama.ProducerMessage{}
 	for _, m := range msgs {
-		saramaMsgs = append(saramaMsgs, &sarama.ProducerMessage{
-			Topic: p.topic,
-			Value: sarama.ByteEncoder(m),
-		})
+		sm, err := p.message(m, nil)
+		if err != nil {
+			return err
+		}
+		saramaMsgs = append(saramaMsgs, sm)
 	}
 	return p.p.SendMessages(saramaMsgs)
 }
 
+func (p *saramaProducer) SendAsync(m *ProducerMsg, report DeliveryReport) {
+	sm, err := p.message(m, report)
+	if err != nil {
+		if report != nil {
+			report(m, Offset{}, err)
+		}
+		return
+	}
+	p.ap.Input() <- sm
+}
+
 func (p *saramaProducer) Close() error {
-	return p.p.Close()
+	p.ap.AsyncClose()
+	<-p.done
+
+	err := p.p.Close()
+	if cerr := p.client.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (p *saramaProducer) message(m *ProducerMsg, report DeliveryReport) (*sarama.ProducerMessage, error) {
+	topic := m.Topic
+	if topic == "" {
+		topic = p.topic
+	}
+	if topic == "" {
+		return nil, errors.New("please set message topic")
+	}
+
+	sm := &sarama.ProducerMessage{
+		Topic:    topic,
+		Value:    sarama.ByteEncoder(m.Value),
+		Metadata: &saramaMeta{msg: m, report: report},
+	}
+	if m.Key != nil {
+		sm.Key = sarama.ByteEncoder(m.Key)
+	}
+	for _, h := range m.Headers {
+		sm.Headers = append(sm.Headers, sarama.RecordHeader{Key: []byte(h.Key), Value: h.Value})
+	}
+	return sm, nil
+}
+
+//reports calls DeliveryReport of async msgs till async producer is closed.
+func (p *saramaProducer) reports() {
+	defer close(p.done)
+
+	successes, errs := p.ap.Successes(), p.ap.Errors()
+	for successes != nil || errs != nil {
+		select {
+		case m, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			report(m, nil)
+		case e, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			report(e.Msg, e.Err)
+		}
+	}
+}
+
+func report(m *sarama.ProducerMessage, err error) {
+	meta, ok := m.Metadata.(*saramaMeta)
+	if !ok || meta.report == nil {
+		return
+	}
+	o := Offset{TopicPartition: TopicPartition{Topic: m.Topic, Partition: m.Partition}, Offset: m.Offset}
+	meta.report(meta.msg, o, err)
+}
+
+//overridePartitioner uses Partition of ProducerMsg when it is set.
+type overridePartitioner struct {
+	sarama.Partitioner
+}
+
+func overridePartitions(c sarama.PartitionerConstructor) sarama.PartitionerConstructor {
+	return func(topic string) sarama.Partitioner {
+		return overridePartitioner{Partitioner: c(topic)}
+	}
+}
+
+func (p overridePartitioner) Partition(m *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if meta, ok := m.Metadata.(*saramaMeta); ok && meta.msg.Partition != nil {
+		if *meta.msg.Partition < 0 || *meta.msg.Partition >= numPartitions {
+			return -1, sarama.ErrInvalidPartition
+		}
+		return *meta.msg.Partition, nil
+	}
+	return p.Partitioner.Partition(m, numPartitions)
+}
+
+//murmur2Partitioner partitions msgs by key as java client does.
+type murmur2Partitioner struct {
+	fallback sarama.Partitioner
+}
+
+func newMurmur2Partitioner(topic string) sarama.Partitioner {
+	return &murmur2Partitioner{fallback: sarama.NewRoundRobinPartitioner(topic)}
+}
+
+func (p *murmur2Partitioner) Partition(m *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if m.Key == nil {
+		return p.fallback.Partition(m, numPartitions)
+	}
+	key, err := m.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+	return int32(murmur2(key)&0x7fffffff) % numPartitions, nil
+}
+
+func (p *murmur2Partitioner) RequiresConsistency() bool {
+	return true
+}
+
+//murmur2 is hash of java client's default partitioner.
+func murmur2(data []byte) uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	n := len(data)
+	h := seed ^ uint32(n)
+
+	for i := 0; i+4 <= n; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := data[n&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
 }
@@ -1,8 +1,11 @@
 package kafka
 
 import (
+	"encoding/json"
+	"github.com/Shopify/sarama"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNewSaramaProducer(t *testing.T) {
@@ -12,3 +15,139 @@ func TestNewSaramaProducer(t *testing.T) {
 	_, err = NewSaramaProducer("test", []string{"lalaland"})
 	assert.Error(t, err)
 }
+
+// testBroker is a broker leading 2 partitions of topic "test"
+func testBroker(t *testing.T) *sarama.MockBroker {
+	b := sarama.NewMockBroker(t, 1)
+
+	res := &sarama.ProduceResponse{Version: 3}
+	res.AddTopicPartition("test", 0, sarama.ErrNoError)
+	res.AddTopicPartition("test", 1, sarama.ErrNoError)
+
+	b.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(b.Addr(), b.BrokerID()).
+			SetLeader("test", 0, b.BrokerID()).
+			SetLeader("test", 1, b.BrokerID()),
+		"ProduceRequest": sarama.NewMockWrapper(res),
+	})
+	return b
+}
+
+func TestSaramaProducer(t *testing.T) {
+	b := testBroker(t)
+	defer b.Close()
+
+	p, err := NewSaramaProducer("test", []string{b.Addr()})
+	assert.NoError(t, err)
+
+	t.Run("write", func(t *testing.T) {
+		assert.NoError(t, p.Write([]json.RawMessage{json.RawMessage(`{"a":1}`)}))
+	})
+
+	t.Run("send", func(t *testing.T) {
+		one := int32(1)
+		err := p.Send([]*ProducerMsg{
+			{Key: []byte("entity"), Value: []byte("a"), Headers: []Header{{Key: "trace", Value: []byte("1")}}},
+			{Value: []byte("b"), Partition: &one},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("send to bad partition", func(t *testing.T) {
+		bad := int32(5)
+		assert.Error(t, p.Send([]*ProducerMsg{{Value: []byte("a"), Partition: &bad}}))
+	})
+
+	t.Run("send without topic", func(t *testing.T) {
+		np := &saramaProducer{}
+		assert.Error(t, np.Send([]*ProducerMsg{{Value: []byte("a")}}))
+	})
+
+	t.Run("send async", func(t *testing.T) {
+		one := int32(1)
+		m := &ProducerMsg{Value: []byte("a"), Partition: &one}
+		reported := make(chan Offset, 1)
+		p.SendAsync(m, func(rm *ProducerMsg, o Offset, err error) {
+			assert.NoError(t, err)
+			assert.Equal(t, m, rm)
+			reported <- o
+		})
+		o := <-reported
+		assert.Equal(t, TopicPartition{Topic: "test", Partition: 1}, o.TopicPartition)
+	})
+
+	assert.NoError(t, p.Close())
+}
+
+func TestSaramaProducerBuilder(t *testing.T) {
+	b := testBroker(t)
+	defer b.Close()
+
+	t.Run("broker not set", func(t *testing.T) {
+		p, err := NewSaramaProducerBuilder("test").Build()
+		assert.Error(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("bad version", func(t *testing.T) {
+		pb := NewSaramaProducerBuilder("test")
+		pb.SetBroker([]string{b.Addr()})
+		pb.SetVersion("lala")
+		_, err := pb.Build()
+		assert.Error(t, err)
+	})
+
+	t.Run("idempotence needs all acks", func(t *testing.T) {
+		pb := NewSaramaProducerBuilder("test")
+		pb.SetBroker([]string{b.Addr()})
+		pb.EnableIdempotence()
+		pb.SetAcks(AcksLeader)
+		_, err := pb.Build()
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown partitioner", func(t *testing.T) {
+		pb := NewSaramaProducerBuilder("test")
+		pb.SetBroker([]string{b.Addr()})
+		pb.SetPartitioner(Partitioner(10))
+		_, err := pb.Build()
+		assert.Error(t, err)
+	})
+
+	t.Run("all good", func(t *testing.T) {
+		pb := NewSaramaProducerBuilder("test")
+		pb.SetBroker([]string{b.Addr()})
+		pb.SetCompression(CompressionSnappy)
+		pb.SetLinger(10 * time.Millisecond)
+		pb.SetBatchSize(1024)
+		pb.SetMaxMessageBytes(1 << 20)
+		pb.SetPartitioner(PartitionerMurmur2)
+		p, err := pb.Build()
+		assert.NoError(t, err)
+
+		reported := make(chan Offset, 1)
+		p.SendAsync(&ProducerMsg{Key: []byte("21"), Value: []byte("a")}, func(m *ProducerMsg, o Offset, err error) {
+			assert.NoError(t, err)
+			reported <- o
+		})
+		//murmur2("21") is -973932308, so it goes to partition 0 of 2
+		assert.Equal(t, int32(0), (<-reported).Partition)
+		assert.NoError(t, p.Close())
+	})
+}
+
+func TestMurmur2(t *testing.T) {
+	//hashes of java client
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for k, v := range cases {
+		assert.Equal(t, v, int32(murmur2([]byte(k))), k)
+	}
+}
@@ -0,0 +1,399 @@
+// Package redis is a delayed job queue on a redis farm. Jobs wait in a
+// sorted set till they are due, are hidden from other consumers for a
+// visibility timeout once dequeued, and move to a dead letter set when they
+// fail more than max retries. A farm of many clusters is used for failover,
+// not replication: a job is enqueued on first cluster that serves it, and
+// jobs are dequeued, reaped and listed from every cluster, so that jobs
+// enqueued while a cluster was down are not left behind once it is back
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alokic/gopkg/gid"
+	farm "github.com/alokic/gopkg/redisfarm"
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	// ErrQueueFarmNotFound : farm is not set
+	ErrQueueFarmNotFound = errors.New("Queue: farm not found")
+
+	// ErrQueueNameNotFound : name is not set
+	ErrQueueNameNotFound = errors.New("Queue: name not found")
+
+	// ErrQueueJobExists : a job with same id is in queue
+	ErrQueueJobExists = errors.New("Queue: job exists")
+
+	// ErrQueueJobNotInFlight : job is acked or its visibility timeout expired
+	ErrQueueJobNotInFlight = errors.New("Queue: job not in flight")
+
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultMaxRetries        = 3
+	defaultReapInterval      = time.Second
+
+	// jobs moved by one reap script call
+	reapBatchSize = 100
+
+	keyspacePrefix = "q"
+
+	// only for test simulation
+	now = time.Now
+)
+
+// Job : a job of queue
+type Job struct {
+	ID         string
+	Record     []byte
+	RetryCount int
+	RunAt      time.Time
+
+	// delivery of job by dequeue, only this delivery can ack or nack it
+	delivery string
+
+	// index of farm cluster job lives on
+	cluster int
+}
+
+// QueueBuilder :
+type QueueBuilder struct {
+	q *Queue
+}
+
+// Queue : redis job queue
+type Queue struct {
+	farm              *farm.Farm
+	name              string
+	keyspace          string
+	visibilityTimeout time.Duration
+	maxRetries        int
+	retryDelay        time.Duration
+	reapInterval      time.Duration
+	ctx               context.Context
+	script            map[string]*redis.Script
+}
+
+// NewQueueBuilder :
+func NewQueueBuilder() *QueueBuilder {
+	return &QueueBuilder{q: &Queue{
+		visibilityTimeout: defaultVisibilityTimeout,
+		maxRetries:        defaultMaxRetries,
+		reapInterval:      defaultReapInterval,
+		ctx:               context.Background(),
+		script:            make(map[string]*redis.Script),
+	}}
+}
+
+// SetFarm :
+func (b *QueueBuilder) SetFarm(f *farm.Farm) *QueueBuilder {
+	b.q.farm = f
+	return b
+}
+
+// SetName : name of queue, queues of same name share their jobs
+func (b *QueueBuilder) SetName(name string) *QueueBuilder {
+	b.q.name = name
+	return b
+}
+
+// SetVisibilityTimeout : time a dequeued job is hidden from other consumers.
+// Job is retried when it is not acked in this time
+func (b *QueueBuilder) SetVisibilityTimeout(d time.Duration) *QueueBuilder {
+	b.q.visibilityTimeout = d
+	return b
+}
+
+// SetMaxRetries : times a failed job is retried before it moves to dead letter set
+func (b *QueueBuilder) SetMaxRetries(n int) *QueueBuilder {
+	b.q.maxRetries = n
+	return b
+}
+
+// SetRetryDelay : delay of a nacked job before it is dequeued again
+func (b *QueueBuilder) SetRetryDelay(d time.Duration) *QueueBuilder {
+	b.q.retryDelay = d
+	return b
+}
+
+// SetReapInterval : interval of reaper retrying jobs whose visibility
+// timeout expired. Reaper is not started when d is 0
+func (b *QueueBuilder) SetReapInterval(d time.Duration) *QueueBuilder {
+	b.q.reapInterval = d
+	return b
+}
+
+// SetContext : reaper runs till ctx is cancelled
+func (b *QueueBuilder) SetContext(ctx context.Context) *QueueBuilder {
+	b.q.ctx = ctx
+	return b
+}
+
+// Build :
+func (b *QueueBuilder) Build() (*Queue, error) {
+	return b.q.build()
+}
+
+func (q *Queue) build() (*Queue, error) {
+	if q.farm == nil {
+		return nil, ErrQueueFarmNotFound
+	}
+	if q.name == "" {
+		return nil, ErrQueueNameNotFound
+	}
+
+	// hash tag keeps every key of queue on one node of a redis cluster
+	q.keyspace = fmt.Sprintf("%s:{%s}", keyspacePrefix, q.name)
+
+	if err := q.loadScript(); err != nil {
+		return nil, err
+	}
+
+	if q.reapInterval > 0 {
+		go q.reaper()
+	}
+	return q, nil
+}
+
+// Enqueue : add job with record, due after delay. A job id is generated when
+// id is empty. Returns id of job
+func (q *Queue) Enqueue(ctx context.Context, id string, record []byte, delay time.Duration) (string, error) {
+	if id == "" {
+		n, err := gid.Get()
+		if err != nil {
+			return "", err
+		}
+		id = strconv.FormatUint(n, 10)
+	}
+
+	reply, err := redis.Int(q.exec(scriptEnqueue, q.readyKey(), q.jobKeyPrefix(), id, record, unixMilli(now().Add(delay))))
+	if err != nil {
+		return "", err
+	}
+	if reply == 0 {
+		return "", ErrQueueJobExists
+	}
+	return id, nil
+}
+
+// Dequeue : take upto n due jobs, oldest first on each cluster. Jobs are
+// hidden from other consumers till they are acked, nacked or their
+// visibility timeout expires
+func (q *Queue) Dequeue(ctx context.Context, n int) ([]*Job, error) {
+	var jobs []*Job
+
+	err := q.each(func(cluster int) error {
+		if len(jobs) >= n {
+			return nil
+		}
+
+		t := now()
+		reply, err := redis.Values(q.execCluster(cluster, scriptDequeue, q.readyKey(), q.inflightKey(), q.jobKeyPrefix(),
+			unixMilli(t), unixMilli(t.Add(q.visibilityTimeout)), n-len(jobs)))
+		if err != nil {
+			return err
+		}
+
+		for _, v := range reply {
+			arr, _ := v.([]interface{})
+			if len(arr) != 5 {
+				continue
+			}
+			j := unmarshallJob(arr[0], arr[2], arr[3], arr[4])
+			d, _ := redis.Int64(arr[1], nil)
+			j.delivery = strconv.FormatInt(d, 10)
+			j.cluster = cluster
+			jobs = append(jobs, j)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Ack : job is done, remove it
+func (q *Queue) Ack(ctx context.Context, j *Job) error {
+	reply, err := redis.Int(q.execCluster(j.cluster, scriptAck, q.inflightKey(), q.jobKeyPrefix(), j.ID, j.delivery))
+	if err != nil {
+		return err
+	}
+	if reply == 0 {
+		return ErrQueueJobNotInFlight
+	}
+	return nil
+}
+
+// Nack : job failed, retry it after retry delay or move it to dead letter
+// set when it failed more than max retries
+func (q *Queue) Nack(ctx context.Context, j *Job) error {
+	t := now()
+	reply, err := redis.Int(q.execCluster(j.cluster, scriptNack, q.inflightKey(), q.readyKey(), q.deadKey(), q.jobKeyPrefix(),
+		j.ID, j.delivery, unixMilli(t), unixMilli(t.Add(q.retryDelay)), q.maxRetries))
+	if err != nil {
+		return err
+	}
+	if reply < 0 {
+		return ErrQueueJobNotInFlight
+	}
+	return nil
+}
+
+// Reap : retry jobs whose visibility timeout expired on every cluster, as if
+// they were nacked without retry delay. Returns number of jobs reaped
+func (q *Queue) Reap(ctx context.Context) (int, error) {
+	total := 0
+
+	err := q.each(func(cluster int) error {
+		for {
+			n, err := redis.Int(q.execCluster(cluster, scriptReap, q.inflightKey(), q.readyKey(), q.deadKey(), q.jobKeyPrefix(),
+				unixMilli(now()), q.maxRetries, reapBatchSize))
+			total += n
+			if err != nil || n < reapBatchSize {
+				return err
+			}
+		}
+	})
+	return total, err
+}
+
+// DeadJobs : upto n jobs of dead letter set, oldest first on each cluster
+func (q *Queue) DeadJobs(ctx context.Context, n int) ([]*Job, error) {
+	var jobs []*Job
+
+	err := q.each(func(cluster int) error {
+		if len(jobs) >= n {
+			return nil
+		}
+
+		reply, err := redis.Values(q.execCluster(cluster, scriptDead, q.deadKey(), q.jobKeyPrefix(), n-len(jobs)))
+		if err != nil {
+			return err
+		}
+
+		for _, v := range reply {
+			arr, _ := v.([]interface{})
+			if len(arr) != 4 {
+				continue
+			}
+			j := unmarshallJob(arr[0], arr[1], arr[2], arr[3])
+			j.cluster = cluster
+			jobs = append(jobs, j)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// reaper : reap every reap interval till ctx is cancelled
+func (q *Queue) reaper() {
+	ticker := time.NewTicker(q.reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := q.Reap(q.ctx); err != nil {
+				log.Println("Error: Queue reaper: ", err.Error())
+			}
+		}
+	}
+}
+
+// exec : run script on queue keyspace of first cluster that serves it
+func (q *Queue) exec(name string, args ...interface{}) (interface{}, error) {
+	return q.farm.Read(q.keyspace, func(conn redis.Conn) (interface{}, error) {
+		return farm.DoScript(conn, q.script[name], args...)
+	})
+}
+
+// execCluster : run script on queue keyspace of cluster
+func (q *Queue) execCluster(cluster int, name string, args ...interface{}) (interface{}, error) {
+	return q.farm.ReadCluster(cluster, q.keyspace, func(conn redis.Conn) (interface{}, error) {
+		return farm.DoScript(conn, q.script[name], args...)
+	})
+}
+
+// each : run fn for every cluster in order. Fails with error of first
+// cluster only when no cluster served, so that a down cluster does not stop
+// the others from being drained
+func (q *Queue) each(fn func(cluster int) error) error {
+	var first error
+	served := false
+
+	for i := 0; i < q.farm.NumClusters(); i++ {
+		if err := fn(i); err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		served = true
+	}
+
+	if served {
+		return nil
+	}
+	return first
+}
+
+// loadScript : scripts of this instance, with placeholders of field names
+// replaced. Scripts are loaded on every server, so that a bad script or an
+// unreachable farm is reported at Build
+func (q *Queue) loadScript() error {
+	r := strings.NewReplacer(
+		"KEYRECORD", keyRecord,
+		"KEYRETRYCOUNT", keyRetryCount,
+		"KEYRUNAT", keyRunAt,
+		"KEYVISTIME", keyVisTime,
+		"KEYDELIVERY", keyDelivery,
+	)
+
+	for name, src := range queueScripts {
+		q.script[name] = redis.NewScript(0, r.Replace(src))
+	}
+
+	return q.farm.LoadScripts(q.script)
+}
+
+func (q *Queue) readyKey() string {
+	return q.keyspace + ":ready"
+}
+
+func (q *Queue) inflightKey() string {
+	return q.keyspace + ":inflight"
+}
+
+func (q *Queue) deadKey() string {
+	return q.keyspace + ":dead"
+}
+
+func (q *Queue) jobKeyPrefix() string {
+	return q.keyspace + ":job:"
+}
+
+func unmarshallJob(id, record, retryCount, runAt interface{}) *Job {
+	j := &Job{}
+	j.ID, _ = redis.String(id, nil)
+	j.Record, _ = redis.Bytes(record, nil)
+	j.RetryCount, _ = redis.Int(retryCount, nil)
+
+	ms, _ := redis.Int64(runAt, nil)
+	j.RunAt = time.Unix(0, ms*int64(time.Millisecond))
+	return j
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,258 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	farm "github.com/alokic/gopkg/redisfarm"
+	"github.com/garyburd/redigo/redis"
+)
+
+var testServer = "redis://localhost:6379"
+
+// testFarm : farm of a cluster per server, testServer by default
+func testFarm(t *testing.T, servers ...string) *farm.Farm {
+	if len(servers) == 0 {
+		servers = []string{testServer}
+	}
+
+	var clusters []*farm.Cluster
+	for _, v := range servers {
+		cl, err := farm.NewClusterBuilder().SetServers([]string{v}).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		clusters = append(clusters, cl)
+	}
+
+	f, err := farm.NewBuilder().SetCluster(clusters).SetWriteQuorum(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func testQueue(t *testing.T, maxRetries int, servers ...string) *Queue {
+	return testNamedQueue(t, fmt.Sprintf("test%d", time.Now().UnixNano()), maxRetries, servers...)
+}
+
+func testNamedQueue(t *testing.T, name string, maxRetries int, servers ...string) *Queue {
+	f := testFarm(t, servers...)
+	q, err := NewQueueBuilder().
+		SetFarm(f).
+		SetName(name).
+		SetVisibilityTimeout(10 * time.Second).
+		SetMaxRetries(maxRetries).
+		SetReapInterval(0).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		f.Write(q.keyspace, func(conn redis.Conn) (interface{}, error) {
+			keys, err := redis.Values(conn.Do("KEYS", q.keyspace+":*"))
+			if err != nil || len(keys) == 0 {
+				return nil, err
+			}
+			return conn.Do("DEL", keys...)
+		})
+	})
+	return q
+}
+
+// testClock : moves clock of queue by d
+func testClock(t *testing.T) func(d time.Duration) {
+	base := time.Now()
+	var lag time.Duration
+	now = func() time.Time { return base.Add(lag) }
+	t.Cleanup(func() { now = time.Now })
+
+	return func(d time.Duration) { lag += d }
+}
+
+func TestQueueBuildErrors(t *testing.T) {
+	if _, err := NewQueueBuilder().SetName("test").Build(); err != ErrQueueFarmNotFound {
+		t.Errorf("Error: TestQueueBuildErrors Got %v, Want %v", err, ErrQueueFarmNotFound)
+	}
+
+	if _, err := NewQueueBuilder().SetFarm(testFarm(t)).Build(); err != ErrQueueNameNotFound {
+		t.Errorf("Error: TestQueueBuildErrors Got %v, Want %v", err, ErrQueueNameNotFound)
+	}
+}
+
+func TestQueueEnqueueAck(t *testing.T) {
+	ctx := context.Background()
+	q := testQueue(t, 3)
+
+	id, err := q.Enqueue(ctx, "", []byte("job"), 0)
+	if err != nil || id == "" {
+		t.Fatalf("Error: TestQueueEnqueueAck Got %q %v, Want an id", id, err)
+	}
+	if _, err := q.Enqueue(ctx, id, []byte("job"), 0); err != ErrQueueJobExists {
+		t.Errorf("Error: TestQueueEnqueueAck Got %v, Want %v", err, ErrQueueJobExists)
+	}
+
+	jobs, err := q.Dequeue(ctx, 10)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != id || string(jobs[0].Record) != "job" {
+		t.Fatalf("Error: TestQueueEnqueueAck Got %v %v, Want job %s", jobs, err, id)
+	}
+
+	if jobs, _ := q.Dequeue(ctx, 10); len(jobs) != 0 {
+		t.Errorf("Error: TestQueueEnqueueAck Got %d jobs, Want in flight job hidden", len(jobs))
+	}
+
+	if err := q.Ack(ctx, jobs[0]); err != nil {
+		t.Errorf("Error: TestQueueEnqueueAck Got %v, Want nil", err)
+	}
+	if err := q.Ack(ctx, jobs[0]); err != ErrQueueJobNotInFlight {
+		t.Errorf("Error: TestQueueEnqueueAck Got %v, Want %v", err, ErrQueueJobNotInFlight)
+	}
+
+	// acked job is removed, so its id can be enqueued again
+	if _, err := q.Enqueue(ctx, id, []byte("job"), 0); err != nil {
+		t.Errorf("Error: TestQueueEnqueueAck Got %v, Want nil", err)
+	}
+}
+
+func TestQueueDelay(t *testing.T) {
+	ctx := context.Background()
+	q := testQueue(t, 3)
+	advance := testClock(t)
+
+	q.Enqueue(ctx, "later", []byte("later"), time.Minute)
+	q.Enqueue(ctx, "now", []byte("now"), 0)
+
+	jobs, _ := q.Dequeue(ctx, 10)
+	if len(jobs) != 1 || jobs[0].ID != "now" {
+		t.Fatalf("Error: TestQueueDelay Got %v, Want only due job", jobs)
+	}
+
+	advance(time.Minute)
+	jobs, _ = q.Dequeue(ctx, 10)
+	if len(jobs) != 1 || jobs[0].ID != "later" {
+		t.Errorf("Error: TestQueueDelay Got %v, Want delayed job", jobs)
+	}
+}
+
+func TestQueueVisibilityTimeout(t *testing.T) {
+	ctx := context.Background()
+	q := testQueue(t, 3)
+	advance := testClock(t)
+
+	q.Enqueue(ctx, "job", []byte("job"), 0)
+	first, _ := q.Dequeue(ctx, 1)
+
+	if n, _ := q.Reap(ctx); n != 0 {
+		t.Errorf("Error: TestQueueVisibilityTimeout Got %d reaped, Want %d", n, 0)
+	}
+
+	advance(q.visibilityTimeout + time.Millisecond)
+	if n, err := q.Reap(ctx); n != 1 || err != nil {
+		t.Fatalf("Error: TestQueueVisibilityTimeout Got %d reaped %v, Want %d", n, err, 1)
+	}
+
+	second, _ := q.Dequeue(ctx, 1)
+	if len(second) != 1 || second[0].RetryCount != 1 {
+		t.Fatalf("Error: TestQueueVisibilityTimeout Got %v, Want job retried once", second)
+	}
+
+	// expired delivery can not ack job of new delivery
+	if err := q.Ack(ctx, first[0]); err != ErrQueueJobNotInFlight {
+		t.Errorf("Error: TestQueueVisibilityTimeout Got %v, Want %v", err, ErrQueueJobNotInFlight)
+	}
+	if err := q.Ack(ctx, second[0]); err != nil {
+		t.Errorf("Error: TestQueueVisibilityTimeout Got %v, Want nil", err)
+	}
+}
+
+func TestQueueNackDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	q := testQueue(t, 2)
+
+	q.Enqueue(ctx, "job", []byte("job"), 0)
+
+	for i := 0; i <= 2; i++ {
+		jobs, _ := q.Dequeue(ctx, 1)
+		if len(jobs) != 1 || jobs[0].RetryCount != i {
+			t.Fatalf("Error: TestQueueNackDeadLetter Got %v, Want retry %d", jobs, i)
+		}
+		if err := q.Nack(ctx, jobs[0]); err != nil {
+			t.Fatalf("Error: TestQueueNackDeadLetter Got %v, Want nil", err)
+		}
+	}
+
+	if jobs, _ := q.Dequeue(ctx, 1); len(jobs) != 0 {
+		t.Errorf("Error: TestQueueNackDeadLetter Got %v, Want no job", jobs)
+	}
+
+	dead, err := q.DeadJobs(ctx, 10)
+	if err != nil || len(dead) != 1 || dead[0].ID != "job" || dead[0].RetryCount != 3 || string(dead[0].Record) != "job" {
+		t.Errorf("Error: TestQueueNackDeadLetter Got %v %v, Want dead job", dead, err)
+	}
+}
+
+func TestQueueReaper(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := testQueue(t, 3)
+	q.visibilityTimeout = 10 * time.Millisecond
+	q.reapInterval = 10 * time.Millisecond
+	q.ctx = ctx
+	go q.reaper()
+
+	q.Enqueue(ctx, "job", []byte("job"), 0)
+	q.Dequeue(ctx, 1)
+
+	for i := 0; i < 100; i++ {
+		if jobs, _ := q.Dequeue(ctx, 1); len(jobs) == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Error: TestQueueReaper Got no job, Want expired job requeued")
+}
+
+func TestQueueDrainsEveryCluster(t *testing.T) {
+	ctx := context.Background()
+	first, second := testServer+"/1", testServer+"/2"
+	name := fmt.Sprintf("test%d", time.Now().UnixNano())
+
+	// job enqueued on second cluster while first one was down
+	down := testNamedQueue(t, name, 3, second)
+	id, err := down.Enqueue(ctx, "", []byte("job"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := testNamedQueue(t, name, 3, first, second)
+	jobs, err := q.Dequeue(ctx, 10)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != id {
+		t.Fatalf("Error: TestQueueDrainsEveryCluster Got %v %v, Want job %s", jobs, err, id)
+	}
+
+	if err := q.Ack(ctx, jobs[0]); err != nil {
+		t.Errorf("Error: TestQueueDrainsEveryCluster Got %v, Want nil", err)
+	}
+}
+
+func TestQueueClusterDown(t *testing.T) {
+	ctx := context.Background()
+	q := testQueue(t, 3, "redis://localhost:1", testServer)
+
+	id, err := q.Enqueue(ctx, "", []byte("job"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := q.Dequeue(ctx, 10)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != id {
+		t.Fatalf("Error: TestQueueClusterDown Got %v %v, Want job %s", jobs, err, id)
+	}
+	if _, err := q.Reap(ctx); err != nil {
+		t.Errorf("Error: TestQueueClusterDown Got %v, Want nil", err)
+	}
+}
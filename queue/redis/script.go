@@ -0,0 +1,148 @@
+package redis
+
+var (
+	// queueRetryScriptStr : prepended to scripts retrying a job. Job is put
+	// back in ready set at runAt, or in dead set when it ran out of retries
+	queueRetryScriptStr = `
+		local keyRetryCount = 'KEYRETRYCOUNT'
+		local keyRunAt = 'KEYRUNAT'
+		local keyVisTime = 'KEYVISTIME'
+
+		local function retry(ready, dead, hkey, id, now, runAt, maxRetries)
+			redis.call("HDEL", hkey, keyVisTime)
+			if redis.call("HINCRBY", hkey, keyRetryCount, 1) > maxRetries then
+				redis.call("ZADD", dead, now, id)
+				return 0
+			end
+
+			redis.call("HSET", hkey, keyRunAt, runAt)
+			redis.call("ZADD", ready, runAt, id)
+			return 1
+		end
+	`
+
+	// ARGV: ready, job key prefix, id, record, run at
+	queueEnqueueScriptStr = `
+		local keyRecord = 'KEYRECORD'
+		local keyRetryCount = 'KEYRETRYCOUNT'
+		local keyRunAt = 'KEYRUNAT'
+		local hkey = ARGV[2] .. ARGV[3]
+
+		if redis.call("EXISTS", hkey) == 1 then
+			return 0
+		end
+
+		redis.call("HMSET", hkey, keyRecord, ARGV[4], keyRetryCount, 0, keyRunAt, ARGV[5])
+		redis.call("ZADD", ARGV[1], ARGV[5], ARGV[3])
+		return 1
+	`
+
+	// ARGV: ready, inflight, job key prefix, now, visibility deadline, count.
+	// Reply is {id, delivery, record, retry count, run at} of every job
+	queueDequeueScriptStr = `
+		local keyRecord = 'KEYRECORD'
+		local keyRetryCount = 'KEYRETRYCOUNT'
+		local keyRunAt = 'KEYRUNAT'
+		local keyVisTime = 'KEYVISTIME'
+		local keyDelivery = 'KEYDELIVERY'
+
+		local jobs = {}
+		local ids = redis.call("ZRANGEBYSCORE", ARGV[1], "-inf", ARGV[4], "LIMIT", 0, ARGV[6])
+
+		for _, id in ipairs(ids) do
+			local hkey = ARGV[3] .. id
+			redis.call("ZREM", ARGV[1], id)
+			redis.call("ZADD", ARGV[2], ARGV[5], id)
+			redis.call("HSET", hkey, keyVisTime, ARGV[5])
+
+			local job = redis.call("HMGET", hkey, keyRecord, keyRetryCount, keyRunAt)
+			jobs[#jobs+1] = {id, redis.call("HINCRBY", hkey, keyDelivery, 1), job[1], job[2], job[3]}
+		end
+
+		return jobs
+	`
+
+	// ARGV: inflight, job key prefix, id, delivery.
+	// A delivery whose visibility expired can not ack the job anymore
+	queueAckScriptStr = `
+		local keyDelivery = 'KEYDELIVERY'
+		local hkey = ARGV[2] .. ARGV[3]
+
+		if redis.call("HGET", hkey, keyDelivery) ~= ARGV[4] or redis.call("ZREM", ARGV[1], ARGV[3]) == 0 then
+			return 0
+		end
+
+		redis.call("DEL", hkey)
+		return 1
+	`
+
+	// ARGV: inflight, ready, dead, job key prefix, id, delivery, now, run at, max retries.
+	// Reply is -1 when delivery is not in flight, 0 when job is dead, 1 when it is retried
+	queueNackScriptStr = `
+		local keyDelivery = 'KEYDELIVERY'
+		local hkey = ARGV[4] .. ARGV[5]
+
+		if redis.call("HGET", hkey, keyDelivery) ~= ARGV[6] or redis.call("ZREM", ARGV[1], ARGV[5]) == 0 then
+			return -1
+		end
+
+		return retry(ARGV[2], ARGV[3], hkey, ARGV[5], ARGV[7], ARGV[8], ARGV[9] + 0)
+	`
+
+	// ARGV: inflight, ready, dead, job key prefix, now, max retries, count.
+	// Reply is number of jobs whose visibility expired
+	queueReapScriptStr = `
+		local ids = redis.call("ZRANGEBYSCORE", ARGV[1], "-inf", ARGV[5], "LIMIT", 0, ARGV[7])
+
+		for _, id in ipairs(ids) do
+			redis.call("ZREM", ARGV[1], id)
+			retry(ARGV[2], ARGV[3], ARGV[4] .. id, id, ARGV[5], ARGV[5], ARGV[6] + 0)
+		end
+
+		return #ids
+	`
+
+	// ARGV: dead, job key prefix, count.
+	// Reply is {id, record, retry count, run at} of oldest dead jobs
+	queueDeadScriptStr = `
+		local keyRecord = 'KEYRECORD'
+		local keyRetryCount = 'KEYRETRYCOUNT'
+		local keyRunAt = 'KEYRUNAT'
+
+		local jobs = {}
+		local ids = redis.call("ZRANGE", ARGV[1], 0, ARGV[3] - 1)
+
+		for _, id in ipairs(ids) do
+			local job = redis.call("HMGET", ARGV[2] .. id, keyRecord, keyRetryCount, keyRunAt)
+			jobs[#jobs+1] = {id, job[1], job[2], job[3]}
+		end
+
+		return jobs
+	`
+
+	// queueScripts : source of every script of a queue instance by name
+	queueScripts = map[string]string{
+		scriptEnqueue: queueEnqueueScriptStr,
+		scriptDequeue: queueDequeueScriptStr,
+		scriptAck:     queueAckScriptStr,
+		scriptNack:    queueRetryScriptStr + queueNackScriptStr,
+		scriptReap:    queueRetryScriptStr + queueReapScriptStr,
+		scriptDead:    queueDeadScriptStr,
+	}
+
+	// job hash fields
+	keyRecord     = "record"
+	keyRetryCount = "retry_count"
+	keyRunAt      = "run_at"
+	keyVisTime    = "vis_time"
+	keyDelivery   = "delivery"
+)
+
+const (
+	scriptEnqueue = "enqueue"
+	scriptDequeue = "dequeue"
+	scriptAck     = "ack"
+	scriptNack    = "nack"
+	scriptReap    = "reap"
+	scriptDead    = "dead"
+)
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Instrumentation wraps every connection dialed by the pool, to trace or
+// measure the commands sent on it. url is the url the connection was dialed to.
+type Instrumentation func(c redis.Conn, url string) redis.Conn
+
+// MetricsHandler is called after every command with its duration and error.
+// Receive is reported with a blank commandName.
+type MetricsHandler func(commandName string, d time.Duration, err error)
+
+// metricsConn reports every command to handler.
+type metricsConn struct {
+	redis.Conn
+	handler MetricsHandler
+}
+
+// WithInstrumentation provides option to wrap every connection of the pool.
+// It can be given more than once, wrappers are applied in order.
+func WithInstrumentation(fn Instrumentation) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.instruments = append(cfg.instruments, fn)
+	}
+}
+
+// WithMetrics provides option to report duration and error of every command.
+func WithMetrics(fn MetricsHandler) PoolOption {
+	return WithInstrumentation(func(c redis.Conn, url string) redis.Conn {
+		return &metricsConn{Conn: c, handler: fn}
+	})
+}
+
+// Do sends a command to the server and reports its duration.
+func (c *metricsConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	start := time.Now()
+	reply, err := c.Conn.Do(commandName, args...)
+
+	c.handler(commandName, time.Since(start), err)
+	return reply, err
+}
+
+// DoWithTimeout sends a command to the server and reports its duration.
+func (c *metricsConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	start := time.Now()
+	reply, err := redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+
+	c.handler(commandName, time.Since(start), err)
+	return reply, err
+}
+
+// Send writes a command to the output buffer and reports its duration.
+func (c *metricsConn) Send(commandName string, args ...interface{}) error {
+	start := time.Now()
+	err := c.Conn.Send(commandName, args...)
+
+	c.handler(commandName, time.Since(start), err)
+	return err
+}
+
+// Receive receives a reply from the server and reports its duration.
+func (c *metricsConn) Receive() (interface{}, error) {
+	start := time.Now()
+	reply, err := c.Conn.Receive()
+
+	c.handler("", time.Since(start), err)
+	return reply, err
+}
+
+// ReceiveWithTimeout receives a reply from the server and reports its duration.
+func (c *metricsConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	start := time.Now()
+	reply, err := redis.ReceiveWithTimeout(c.Conn, timeout)
+
+	c.handler("", time.Since(start), err)
+	return reply, err
+}
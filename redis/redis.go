@@ -1,35 +1,81 @@
 package redis
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 )
 
+var (
+	// ErrDialerConflict is returned by dial of a pool given more than one dialer,
+	// like WithDialer and an option built on it.
+	ErrDialerConflict = errors.New("redis: pool dialer set more than once")
+)
+
 type poolConfig struct {
-	maxIdleConns   int
-	maxActiveConns int
-	idleTimeout    int // seconds
+	maxIdleConns    int
+	maxActiveConns  int
+	idleTimeout     int // seconds
+	maxConnLifetime int // seconds
+	sentinel        *Sentinel
+	replica         bool
+	dialer          Dialer
+	dialerSet       bool
+	instruments     []Instrumentation
+	err             error
 }
 
+// Dialer dials a connection to url with the dial options given to NewRedisPool.
+type Dialer func(url string, opts ...interface{}) (redis.Conn, error)
+
 //
 type PoolOption func(*poolConfig)
 
-// NewRedisPool returns a pool of redis connections.
+// NewRedisPool returns a pool of redis connections. opts can be PoolOption or
+// redis.DialOption, any other option fails the dial. With WithSentinel option
+// host of url is replaced by the address resolved from sentinels.
 func NewRedisPool(url string, opts ...interface{}) *redis.Pool {
-	dialOpts, cfg := parsePoolOptions(opts)
+	dialOpts, cfg := parsePoolOptions(opts...)
 
 	return &redis.Pool{
-		MaxIdle:     cfg.maxIdleConns,
-		MaxActive:   cfg.maxActiveConns,
-		Wait:        true,
-		IdleTimeout: time.Duration(cfg.idleTimeout) * time.Second,
+		MaxIdle:         cfg.maxIdleConns,
+		MaxActive:       cfg.maxActiveConns,
+		Wait:            true,
+		IdleTimeout:     time.Duration(cfg.idleTimeout) * time.Second,
+		MaxConnLifetime: time.Duration(cfg.maxConnLifetime) * time.Second,
 
 		Dial: func() (redis.Conn, error) {
-			return redis.DialURL(url, dialOpts...)
+			if cfg.err != nil {
+				return nil, cfg.err
+			}
+
+			u := url
+			if cfg.sentinel != nil {
+				// resolve on every dial, so the pool follows failover
+				var err error
+				if u, err = cfg.sentinel.DialURL(url, cfg.replica); err != nil {
+					return nil, err
+				}
+			}
+
+			c, err := cfg.dialer(u, dialOpts...)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, fn := range cfg.instruments {
+				c = fn(c, u)
+			}
+			return c, nil
 		},
 
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if cfg.sentinel != nil {
+				return TestRole(c, cfg.replica)
+			}
+
 			_, err := c.Do("PING")
 			return err
 		},
@@ -57,8 +103,44 @@ func WithIdleTimeout(seconds int) PoolOption {
 	}
 }
 
-func parsePoolOptions(options ...interface{}) ([]redis.DialOption, *poolConfig) {
-	var dialOpts []redis.DialOption
+// WithMaxConnLifetime provides option to close connections older than seconds.
+func WithMaxConnLifetime(seconds int) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.maxConnLifetime = seconds
+	}
+}
+
+// WithDialer provides option to dial connections with d instead of
+// redis.DialURL. Options which are not PoolOption are passed to d. A pool
+// given more than one dialer fails every dial with ErrDialerConflict.
+func WithDialer(d Dialer) PoolOption {
+	return func(cfg *poolConfig) {
+		if cfg.dialerSet {
+			cfg.err = ErrDialerConflict
+		}
+		cfg.dialer = d
+		cfg.dialerSet = true
+	}
+}
+
+// WithSentinel provides option to dial the master of a sentinel monitored
+// service. Master is resolved again after failover.
+func WithSentinel(s *Sentinel) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.sentinel = s
+	}
+}
+
+// WithReplicaReads provides option to dial replicas of the sentinel monitored
+// service instead of master, for read-only traffic. Needs WithSentinel.
+func WithReplicaReads() PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.replica = true
+	}
+}
+
+func parsePoolOptions(options ...interface{}) ([]interface{}, *poolConfig) {
+	var dialOpts []interface{}
 
 	cfg := new(poolConfig)
 	redifPoolDefaults(cfg)
@@ -67,15 +149,30 @@ func parsePoolOptions(options ...interface{}) ([]redis.DialOption, *poolConfig)
 		switch o := opt.(type) {
 		case PoolOption:
 			o(cfg)
-		case redis.DialOption:
+		default:
 			dialOpts = append(dialOpts, o)
 		}
 	}
 	return dialOpts, cfg
 }
 
+// dialURL is the default Dialer, it accepts only redis.DialOption.
+func dialURL(url string, opts ...interface{}) (redis.Conn, error) {
+	dialOpts := make([]redis.DialOption, 0, len(opts))
+
+	for _, opt := range opts {
+		o, ok := opt.(redis.DialOption)
+		if !ok {
+			return nil, fmt.Errorf("Unknown redis pool option %T", opt)
+		}
+		dialOpts = append(dialOpts, o)
+	}
+	return redis.DialURL(url, dialOpts...)
+}
+
 func redifPoolDefaults(cfg *poolConfig) {
 	cfg.maxIdleConns = 10
 	cfg.maxActiveConns = 10
 	cfg.idleTimeout = 240
+	cfg.dialer = dialURL
 }
@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	testServer = "redis://localhost:6379"
+)
+
+func TestPoolOptions(t *testing.T) {
+	p := NewRedisPool(testServer, WithMaxIdleConns(2), WithMaxActiveConns(3), WithIdleTimeout(5), WithMaxConnLifetime(60))
+
+	if p.MaxIdle != 2 || p.MaxActive != 3 || p.IdleTimeout != 5*time.Second || p.MaxConnLifetime != time.Minute {
+		t.Errorf("Error: TestPoolOptions options not applied Got %d %d %s %s", p.MaxIdle, p.MaxActive, p.IdleTimeout, p.MaxConnLifetime)
+	}
+}
+
+func TestPoolMetrics(t *testing.T) {
+	var cmds []string
+
+	p := NewRedisPool(testServer, WithMetrics(func(commandName string, d time.Duration, err error) {
+		cmds = append(cmds, commandName)
+	}))
+	defer p.Close()
+
+	c := p.Get()
+	defer c.Close()
+
+	if _, err := c.Do("PING"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(cmds) != 1 || cmds[0] != "PING" {
+		t.Errorf("Error: TestPoolMetrics Got %v, Want %v", cmds, []string{"PING"})
+	}
+}
+
+func TestPoolInstrumentationOrder(t *testing.T) {
+	var order []int
+
+	wrap := func(i int) Instrumentation {
+		return func(c redis.Conn, url string) redis.Conn {
+			order = append(order, i)
+			return c
+		}
+	}
+
+	p := NewRedisPool(testServer, WithInstrumentation(wrap(1)), WithInstrumentation(wrap(2)))
+	defer p.Close()
+
+	c := p.Get()
+	defer c.Close()
+	c.Do("PING")
+
+	if len(order) != 2 || order[0] != 1 || order[1] != 2 {
+		t.Errorf("Error: TestPoolInstrumentationOrder Got %v, Want %v", order, []int{1, 2})
+	}
+}
+
+func TestPoolUnknownOption(t *testing.T) {
+	p := NewRedisPool(testServer, "unknown")
+	defer p.Close()
+
+	c := p.Get()
+	defer c.Close()
+
+	if _, err := c.Do("PING"); err == nil {
+		t.Errorf("Error: TestPoolUnknownOption Got %v, Want error", err)
+	}
+}
+
+func TestPoolDialerConflict(t *testing.T) {
+	d := WithDialer(func(url string, opts ...interface{}) (redis.Conn, error) {
+		return redis.DialURL(url)
+	})
+
+	p := NewRedisPool(testServer, d, d)
+	defer p.Close()
+
+	if _, err := p.Dial(); err != ErrDialerConflict {
+		t.Errorf("Error: TestPoolDialerConflict Got %v, Want %v", err, ErrDialerConflict)
+	}
+}
+
+func TestPoolMetricsPipeline(t *testing.T) {
+	var cmds []string
+
+	p := NewRedisPool(testServer, WithMetrics(func(commandName string, d time.Duration, err error) {
+		cmds = append(cmds, commandName)
+	}))
+	defer p.Close()
+
+	c := p.Get()
+	defer c.Close()
+
+	c.Send("PING")
+	c.Flush()
+	if _, err := c.Receive(); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := redis.DoWithTimeout(c, time.Second, "PING"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := []string{"PING", "", "PING"}
+	if len(cmds) != len(want) || cmds[0] != want[0] || cmds[1] != want[1] || cmds[2] != want[2] {
+		t.Errorf("Error: TestPoolMetricsPipeline Got %q, Want %q", cmds, want)
+	}
+}
@@ -0,0 +1,171 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	// ErrNoSentinel is returned when no sentinel could be reached.
+	ErrNoSentinel = errors.New("redis: no sentinel reachable")
+
+	// ErrNoReplica is returned when sentinels know no healthy replica.
+	ErrNoReplica = errors.New("redis: no healthy replica")
+
+	sentinelTimeout = time.Second
+)
+
+// Sentinel resolves the current master and replicas of a named service
+// from a list of sentinels. It is safe for concurrent use.
+type Sentinel struct {
+	masterName string
+
+	mu    sync.Mutex
+	addrs []string
+}
+
+// NewSentinel returns a Sentinel for masterName asking sentinels at addrs (host:port).
+func NewSentinel(masterName string, addrs ...string) *Sentinel {
+	return &Sentinel{masterName: masterName, addrs: addrs}
+}
+
+// MasterAddr returns host:port of the current master.
+func (s *Sentinel) MasterAddr() (string, error) {
+	var addr string
+
+	err := s.query(func(c redis.Conn) error {
+		res, err := redis.Strings(c.Do("SENTINEL", "get-master-addr-by-name", s.masterName))
+		if err != nil {
+			return err
+		}
+		if len(res) != 2 {
+			return fmt.Errorf("redis: sentinel does not know master %s", s.masterName)
+		}
+
+		addr = res[0] + ":" + res[1]
+		return nil
+	})
+	return addr, err
+}
+
+// ReplicaAddr returns host:port of a random healthy replica.
+func (s *Sentinel) ReplicaAddr() (string, error) {
+	var addrs []string
+
+	err := s.query(func(c redis.Conn) error {
+		replicas, err := redis.Values(c.Do("SENTINEL", "slaves", s.masterName))
+		if err != nil {
+			return err
+		}
+
+		addrs = addrs[:0]
+		for _, v := range replicas {
+			m, err := redis.StringMap(v, nil)
+			if err != nil {
+				return err
+			}
+			if replicaHealthy(m["flags"]) {
+				addrs = append(addrs, m["ip"]+":"+m["port"])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(addrs) == 0 {
+		return "", ErrNoReplica
+	}
+	return addrs[rand.Intn(len(addrs))], nil
+}
+
+// query runs fn on sentinels in order till one succeeds. The sentinel which
+// answered is moved to front, so it is asked first next time.
+func (s *Sentinel) query(fn func(redis.Conn) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := ErrNoSentinel
+	for i, addr := range s.addrs {
+		c, e := redis.Dial("tcp", addr,
+			redis.DialConnectTimeout(sentinelTimeout),
+			redis.DialReadTimeout(sentinelTimeout),
+			redis.DialWriteTimeout(sentinelTimeout))
+		if e != nil {
+			err = e
+			continue
+		}
+
+		e = fn(c)
+		c.Close()
+		if e != nil {
+			err = e
+			continue
+		}
+
+		s.addrs[0], s.addrs[i] = s.addrs[i], s.addrs[0]
+		return nil
+	}
+	return err
+}
+
+// DialURL returns rawurl with host replaced by the address of master, or of a
+// replica when replica is true, resolved from sentinels.
+func (s *Sentinel) DialURL(rawurl string, replica bool) (string, error) {
+	resolve := s.MasterAddr
+	if replica {
+		resolve = s.ReplicaAddr
+	}
+
+	addr, err := resolve()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	u.Host = addr
+	return u.String(), nil
+}
+
+// TestRole returns error when server of c is not a master, or not a replica
+// when replica is true, so that the pool drops connections after failover.
+func TestRole(c redis.Conn, replica bool) error {
+	res, err := redis.Values(c.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+
+	want := "master"
+	if replica {
+		want = "slave"
+	}
+
+	if len(res) == 0 {
+		return errors.New("redis: empty ROLE reply")
+	}
+	if role, _ := redis.String(res[0], nil); role != want {
+		return fmt.Errorf("redis: server role is %s, want %s", role, want)
+	}
+	return nil
+}
+
+func replicaHealthy(flags string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		switch f {
+		case "s_down", "o_down", "disconnected":
+			return false
+		}
+	}
+	return true
+}
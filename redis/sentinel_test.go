@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testSentinel : fake sentinel answering master and replica queries
+type testSentinel struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	master   []string
+	replicas [][]string // ip, port, flags
+}
+
+func newTestSentinel(t *testing.T) *testSentinel {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testSentinel{ln: ln}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *testSentinel) setMaster(ip, port string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.master = []string{ip, port}
+}
+
+func (s *testSentinel) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		var n int
+		fmt.Sscanf(line, "*%d", &n)
+		args := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			r.ReadString('\n')
+			arg, _ := r.ReadString('\n')
+			args = append(args, strings.TrimSpace(arg))
+		}
+
+		s.mu.Lock()
+		switch strings.ToLower(args[1]) {
+		case "get-master-addr-by-name":
+			fmt.Fprintf(c, "*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(s.master[0]), s.master[0], len(s.master[1]), s.master[1])
+		case "slaves":
+			fmt.Fprintf(c, "*%d\r\n", len(s.replicas))
+			for _, v := range s.replicas {
+				fmt.Fprintf(c, "*6\r\n$2\r\nip\r\n$%d\r\n%s\r\n$4\r\nport\r\n$%d\r\n%s\r\n$5\r\nflags\r\n$%d\r\n%s\r\n",
+					len(v[0]), v[0], len(v[1]), v[1], len(v[2]), v[2])
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestSentinelMasterAddr(t *testing.T) {
+	s := newTestSentinel(t)
+	defer s.ln.Close()
+	s.setMaster("127.0.0.1", "6379")
+
+	// first sentinel is down
+	sentinel := NewSentinel("mymaster", "127.0.0.1:1", s.ln.Addr().String())
+
+	addr, err := sentinel.MasterAddr()
+	if err != nil || addr != "127.0.0.1:6379" {
+		t.Errorf("Error: TestSentinelMasterAddr Got %s %v, Want %s", addr, err, "127.0.0.1:6379")
+		return
+	}
+
+	// failover
+	s.setMaster("127.0.0.2", "6380")
+	u, err := sentinel.DialURL("redis://:secret@ignored:1/2", false)
+	if err != nil || u != "redis://:secret@127.0.0.2:6380/2" {
+		t.Errorf("Error: TestSentinelMasterAddr Got %s %v, Want new master in url", u, err)
+	}
+}
+
+func TestSentinelReplicaAddr(t *testing.T) {
+	s := newTestSentinel(t)
+	defer s.ln.Close()
+	s.replicas = [][]string{
+		{"127.0.0.3", "6379", "slave,s_down"},
+		{"127.0.0.4", "6379", "slave"},
+	}
+
+	addr, err := NewSentinel("mymaster", s.ln.Addr().String()).ReplicaAddr()
+	if err != nil || addr != "127.0.0.4:6379" {
+		t.Errorf("Error: TestSentinelReplicaAddr Got %s %v, Want healthy replica", addr, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.replicas = s.replicas[:1]
+	s.mu.Unlock()
+
+	if _, err := NewSentinel("mymaster", s.ln.Addr().String()).ReplicaAddr(); err != ErrNoReplica {
+		t.Errorf("Error: TestSentinelReplicaAddr Got %v, Want %v", err, ErrNoReplica)
+	}
+}
+
+func TestSentinelPool(t *testing.T) {
+	s := newTestSentinel(t)
+	defer s.ln.Close()
+	s.setMaster("localhost", "6379")
+
+	p := NewRedisPool("redis://ignored:1", WithSentinel(NewSentinel("mymaster", s.ln.Addr().String())))
+	defer p.Close()
+
+	c := p.Get()
+	defer c.Close()
+
+	if _, err := c.Do("PING"); err != nil {
+		t.Error(err)
+	}
+}
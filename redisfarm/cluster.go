@@ -1,7 +1,9 @@
 package redisfarm
 
 import (
+	"crypto/tls"
 	"errors"
+	"time"
 
 	"github.com/garyburd/redigo/redis"
 )
@@ -10,7 +12,11 @@ var (
 	// ErrClusterNoConn : "No conn exists"
 	ErrClusterNoConn = errors.New("No conn exists")
 
-	defaultIdleConns = 6
+	// ErrClusterNoHealthyConn : "No healthy conn exists"
+	ErrClusterNoHealthyConn = errors.New("No healthy conn exists")
+
+	// ErrClusterModeHealthCheck : "Health check is not supported in cluster mode"
+	ErrClusterModeHealthCheck = errors.New("Health check is not supported in cluster mode")
 )
 
 // ConnSelectHandler : Handler to select shard
@@ -23,10 +29,20 @@ type ClusterBuilder struct {
 
 // Cluster :
 type Cluster struct {
-	conns     []redis.Conn
-	maxIdle   int
-	maxActive int
-	CS        ConnSelectHandler
+	conns    []redis.Conn
+	servers  []string
+	cfg      *poolConfig
+	replicas int
+	ring     *hashRing
+	CS       ConnSelectHandler
+
+	clusterMode bool
+	slots       *slotRouter
+
+	health         *healthChecker
+	healthInterval time.Duration
+	healthFailures int
+	healthHandler  HealthHandler
 }
 
 // NewClusterBuilder :
@@ -36,31 +52,72 @@ func NewClusterBuilder() *ClusterBuilder {
 
 // SetServers :
 func (c *ClusterBuilder) SetServers(servers []string) *ClusterBuilder {
-	maxIdle, maxActive := c.c.maxIdle, c.c.maxActive
-
-	if maxIdle == 0 {
-		maxIdle = defaultIdleConns
-	}
-
-	for _, v := range servers {
-		c.c.conns = append(c.c.conns, createConn(v, maxIdle, maxActive))
-	}
+	c.c.servers = append(c.c.servers, servers...)
 	return c
 }
 
 // SetMaxIdleConns :
 func (c *ClusterBuilder) SetMaxIdleConns(n int) *ClusterBuilder {
-	c.c.maxIdle = n
+	c.c.cfg.maxIdle = n
 	return c
 }
 
-// SetMaxActiveConns :
+// SetMaxActiveConns : defaults to max idle conns
 func (c *ClusterBuilder) SetMaxActiveConns(n int) *ClusterBuilder {
-	c.c.maxActive = n
+	c.c.cfg.maxActive = n
+	return c
+}
+
+// SetIdleTimeout : idle conns are closed after d, defaults to 240s
+func (c *ClusterBuilder) SetIdleTimeout(d time.Duration) *ClusterBuilder {
+	c.c.cfg.idleTimeout = d
+	return c
+}
+
+// SetMaxConnLifetime : conns older than d are closed on borrow
+func (c *ClusterBuilder) SetMaxConnLifetime(d time.Duration) *ClusterBuilder {
+	c.c.cfg.maxConnLifetime = d
+	return c
+}
+
+// SetDialTimeout :
+func (c *ClusterBuilder) SetDialTimeout(d time.Duration) *ClusterBuilder {
+	c.c.cfg.dialTimeout = d
+	return c
+}
+
+// SetReadTimeout : not applied to subscriber conns
+func (c *ClusterBuilder) SetReadTimeout(d time.Duration) *ClusterBuilder {
+	c.c.cfg.readTimeout = d
+	return c
+}
+
+// SetWriteTimeout :
+func (c *ClusterBuilder) SetWriteTimeout(d time.Duration) *ClusterBuilder {
+	c.c.cfg.writeTimeout = d
+	return c
+}
+
+// SetTLSConfig : used for servers with rediss:// url
+func (c *ClusterBuilder) SetTLSConfig(cfg *tls.Config) *ClusterBuilder {
+	c.c.cfg.tlsConfig = cfg
+	return c
+}
+
+// SetPassword : password in server url takes precedence
+func (c *ClusterBuilder) SetPassword(password string) *ClusterBuilder {
+	c.c.cfg.password = password
+	return c
+}
+
+// SetReplicas : number of virtual nodes per server in consistent hash ring
+func (c *ClusterBuilder) SetReplicas(n int) *ClusterBuilder {
+	c.c.replicas = n
 	return c
 }
 
-// SetConnSelectHandler :
+// SetConnSelectHandler : overrides default consistent hash handler.
+// fn is not aware of server health
 func (c *ClusterBuilder) SetConnSelectHandler(fn ConnSelectHandler) *ClusterBuilder {
 	c.c.CS = fn
 	return c
@@ -72,19 +129,64 @@ func (c *ClusterBuilder) Build() (*Cluster, error) {
 }
 
 func newCluster() *Cluster {
-	return &Cluster{conns: make([]redis.Conn, 0), CS: defaultConnSelectHandler}
+	return &Cluster{conns: make([]redis.Conn, 0), cfg: newPoolConfig()}
 }
 
 func (c *Cluster) build() (*Cluster, error) {
-	if len(c.conns) == 0 {
+	if len(c.servers) == 0 {
 		return nil, ErrClusterNoConn
 	}
+
+	if c.clusterMode {
+		if c.healthInterval > 0 || c.healthHandler != nil {
+			return nil, ErrClusterModeHealthCheck
+		}
+		return c.buildClusterMode()
+	}
+
+	for _, v := range c.servers {
+		c.conns = append(c.conns, createConn(v, c.cfg))
+	}
+
+	c.ring = newHashRing(c.replicas)
+	for i, v := range c.servers {
+		c.ring.add(i, v)
+	}
+
+	c.health = newHealthChecker(len(c.servers))
+	c.health.interval, c.health.handler = c.healthInterval, c.healthHandler
+	if c.healthFailures > 0 {
+		c.health.failures = c.healthFailures
+	}
+
+	if c.CS == nil {
+		c.CS = func(key string) (int, error) {
+			return c.ring.next(key, c.health.isHealthy)
+		}
+	}
+
+	c.health.start(c)
 	return c, nil
 }
 
-// GetConn : implements consistent hash
-// TODO
+func (c *Cluster) buildClusterMode() (*Cluster, error) {
+	slots, err := newSlotRouter(c.servers, c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.slots = slots
+	c.health = newHealthChecker(0)
+	return c, nil
+}
+
+// GetConn : selects conn using ConnSelectHandler, consistent hash by default.
+// In cluster mode conn of master owning hash slot of keyspace is returned
 func (c *Cluster) GetConn(keyspace string) (redis.Conn, error) {
+	if c.slots != nil {
+		return c.slots.getConn(keyspace)
+	}
+
 	idx, err := c.CS(keyspace)
 	if err != nil {
 		return nil, err
@@ -92,11 +194,54 @@ func (c *Cluster) GetConn(keyspace string) (redis.Conn, error) {
 	return c.conns[idx], nil
 }
 
-// AllConn : return all connection
+// ClusterMode : whether servers are nodes of a native Redis Cluster
+func (c *Cluster) ClusterMode() bool {
+	return c.slots != nil
+}
+
+// AllConn : return all healthy connection, all masters in cluster mode
 func (c *Cluster) AllConn() ([]redis.Conn, error) {
-	return c.conns, nil
+	if c.slots != nil {
+		return c.slots.allConn(), nil
+	}
+
+	conns := make([]redis.Conn, 0, len(c.conns))
+	for i, v := range c.conns {
+		if c.health.isHealthy(i) {
+			conns = append(conns, v)
+		}
+	}
+
+	if len(conns) == 0 {
+		return nil, ErrClusterNoHealthyConn
+	}
+	return conns, nil
 }
 
-func defaultConnSelectHandler(s string) (int, error) {
-	return 0, nil
+// Close : stops health checker and closes conn pools
+func (c *Cluster) Close() error {
+	c.health.close()
+
+	var err error
+	for _, v := range c.serverConns() {
+		if e := v.pool.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// serverConns : conn of every server, discovered nodes in cluster mode
+func (c *Cluster) serverConns() []*conn {
+	if c.slots != nil {
+		return c.slots.nodeList()
+	}
+
+	conns := make([]*conn, 0, len(c.conns))
+	for _, v := range c.conns {
+		if sc, ok := v.(*conn); ok {
+			conns = append(conns, sc)
+		}
+	}
+	return conns
 }
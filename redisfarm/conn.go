@@ -2,7 +2,6 @@ package redisfarm
 
 import (
 	"log"
-	"time"
 
 	"github.com/garyburd/redigo/redis"
 )
@@ -24,50 +23,30 @@ type conn struct {
 	// script Map
 	scriptMap map[string]ScriptHandler
 
-	// maxIdle
-	maxIdle int
-
-	// maxActive
-	maxActive int
+	// pool config
+	cfg *poolConfig
 }
 
 // ScriptHandler : signature of script handler
 type ScriptHandler func(...interface{}) (interface{}, error)
 
-func createConn(s string, maxIdle, maxActive int) redis.Conn {
+func createConn(s string, cfg *poolConfig) redis.Conn {
 	r := &conn{
-		url:       s,
-		maxIdle:   maxIdle,
-		maxActive: maxActive,
+		url: s,
+		cfg: cfg,
 	}
 
-	r.pool = newpool(r.url, maxIdle, maxActive)
+	r.pool = newpool(r)
 	return r
 }
 
-// create conn pool
-func newpool(url string, maxIdle, maxActive int) *redis.Pool {
-	idleTimeout := 240 * time.Second
-
-	return &redis.Pool{
-		MaxIdle:     maxIdle,
-		MaxActive:   maxIdle,
-		Wait:        true,
-		IdleTimeout: idleTimeout,
-
-		Dial: func() (redis.Conn, error) {
-			c, err := redis.DialURL(url)
-			if err != nil {
-				log.Printf("Error: Subscriber conn can not be created: %s", err.Error())
-			}
-			return c, err
-		},
-
-		TestOnBorrow: func(c redis.Conn, t time.Time) error {
-			_, err := c.Do("PING")
-			return err
-		},
+// dial : new connection to server, options override the pool config
+func (c *conn) dial(opts ...redis.DialOption) (redis.Conn, error) {
+	rc, err := redis.DialURL(c.url, append(c.cfg.dialOptions(), opts...)...)
+	if err != nil {
+		log.Printf("Error: conn can not be created: %s", err.Error())
 	}
+	return rc, err
 }
 
 // Close closes the conn.
@@ -95,6 +74,7 @@ func (c *conn) Do(commandName string, args ...interface{}) (reply interface{}, e
 }
 
 // Send writes the command to the client's output buffer.
+// Each call takes a connection from pool, use Pipeline for pipelining
 func (c *conn) Send(commandName string, args ...interface{}) error {
 	conn := c.pool.Get()
 	defer conn.Close()
@@ -110,7 +90,8 @@ func (c *conn) Flush() error {
 	return conn.Flush()
 }
 
-// Receive receives a single reply from the Redis server
+// Receive receives a single reply from the Redis server.
+// Each call takes a connection from pool, use Pipeline for pipelining
 func (c *conn) Receive() (reply interface{}, err error) {
 	conn := c.pool.Get()
 	defer conn.Close()
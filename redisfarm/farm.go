@@ -9,6 +9,9 @@ import (
 var (
 	// ErrFarmNoCluster : "No cluster exists"
 	ErrFarmNoCluster = errors.New("No cluster exists")
+
+	// ErrFarmInvalidQuorum : "Write quorum is more than clusters"
+	ErrFarmInvalidQuorum = errors.New("Write quorum is more than clusters")
 )
 
 // Builder :
@@ -18,7 +21,8 @@ type Builder struct {
 
 // Farm :
 type Farm struct {
-	clusters []*Cluster
+	clusters    []*Cluster
+	writeQuorum int
 }
 
 // NewBuilder :
@@ -32,6 +36,12 @@ func (f *Builder) SetCluster(clusters []*Cluster) *Builder {
 	return f
 }
 
+// SetWriteQuorum : number of clusters which must ack a write, defaults to all clusters
+func (f *Builder) SetWriteQuorum(n int) *Builder {
+	f.f.writeQuorum = n
+	return f
+}
+
 // Build :
 func (f *Builder) Build() (*Farm, error) {
 	return f.f.build()
@@ -45,11 +55,28 @@ func (f *Farm) build() (*Farm, error) {
 	if len(f.clusters) == 0 {
 		return nil, ErrFarmNoCluster
 	}
+
+	if f.writeQuorum <= 0 {
+		f.writeQuorum = len(f.clusters)
+	}
+	if f.writeQuorum > len(f.clusters) {
+		return nil, ErrFarmInvalidQuorum
+	}
 	return f, nil
 }
 
-// GetConn : implements consistent hash
-// TODO
+// ClusterMode : whether any cluster is a native Redis Cluster, where a
+// command can only touch keys of the node it is sent to
+func (f *Farm) ClusterMode() bool {
+	for _, v := range f.clusters {
+		if v.ClusterMode() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetConn : conn of keyspace from every cluster, each cluster picks its shard
 func (f *Farm) GetConn(keyspace string) redis.Conn {
 	mc := &multiConn{}
 
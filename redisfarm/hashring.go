@@ -0,0 +1,78 @@
+package redisfarm
+
+import (
+	"errors"
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+var (
+	// ErrHashRingEmpty : "Hash ring has no node"
+	ErrHashRingEmpty = errors.New("Hash ring has no node")
+
+	// ErrHashRingNoHealthyNode : "Hash ring has no healthy node"
+	ErrHashRingNoHealthyNode = errors.New("Hash ring has no healthy node")
+
+	defaultReplicas = 160
+)
+
+// hashRing : consistent hash ring with virtual nodes.
+// Virtual nodes are derived from server url (not position), so placement is
+// stable across restarts and reordering of servers, and adding/removing a
+// server only moves the keys owned by that server.
+type hashRing struct {
+	replicas int
+	points   []uint32       // sorted hash of virtual nodes
+	owner    map[uint32]int // virtual node hash => conn index
+}
+
+func newHashRing(replicas int) *hashRing {
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+	return &hashRing{replicas: replicas, owner: make(map[uint32]int)}
+}
+
+// add : add a node at index idx identified by name
+func (h *hashRing) add(idx int, name string) {
+	for i := 0; i < h.replicas; i++ {
+		p := hashKey(name + "#" + strconv.Itoa(i))
+
+		// on (rare) collision keep the first owner so placement stays deterministic
+		if _, ok := h.owner[p]; ok {
+			continue
+		}
+		h.owner[p] = idx
+		h.points = append(h.points, p)
+	}
+	sort.Slice(h.points, func(i, j int) bool { return h.points[i] < h.points[j] })
+}
+
+// get : index of the node owning key
+func (h *hashRing) get(key string) (int, error) {
+	return h.next(key, nil)
+}
+
+// next : index of the node owning key, skipping nodes for which ok is false.
+// Keys of a skipped node move to the next node on ring, others do not move
+func (h *hashRing) next(key string, ok func(int) bool) (int, error) {
+	if len(h.points) == 0 {
+		return 0, ErrHashRingEmpty
+	}
+
+	p := hashKey(key)
+	i := sort.Search(len(h.points), func(i int) bool { return h.points[i] >= p })
+
+	for n := 0; n < len(h.points); n++ {
+		idx := h.owner[h.points[(i+n)%len(h.points)]]
+		if ok == nil || ok(idx) {
+			return idx, nil
+		}
+	}
+	return 0, ErrHashRingNoHealthyNode
+}
+
+func hashKey(s string) uint32 {
+	return crc32.ChecksumIEEE([]byte(s))
+}
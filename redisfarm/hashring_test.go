@@ -0,0 +1,107 @@
+package redisfarm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testRing(servers []string) *hashRing {
+	h := newHashRing(0)
+	for i, s := range servers {
+		h.add(i, s)
+	}
+	return h
+}
+
+func TestHashRingEmpty(t *testing.T) {
+	if _, err := newHashRing(0).get("key"); err != ErrHashRingEmpty {
+		t.Errorf("Error: TestHashRingEmpty Got %v, Want %v", err, ErrHashRingEmpty)
+	}
+}
+
+func TestHashRingDistribution(t *testing.T) {
+	servers := []string{"redis://a:6379", "redis://b:6379", "redis://c:6379", "redis://d:6379"}
+	h := testRing(servers)
+
+	numKeys := 10000
+	count := make([]int, len(servers))
+	for i := 0; i < numKeys; i++ {
+		idx, err := h.get(fmt.Sprintf("key:%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		count[idx]++
+	}
+
+	// every server should own a fair share of keys
+	for i, n := range count {
+		if n < numKeys/len(servers)/2 {
+			t.Errorf("Error: TestHashRingDistribution server %s got only %d of %d keys", servers[i], n, numKeys)
+		}
+	}
+}
+
+func TestHashRingStableOrder(t *testing.T) {
+	h1 := testRing([]string{"redis://a:6379", "redis://b:6379", "redis://c:6379"})
+	h2 := testRing([]string{"redis://c:6379", "redis://a:6379", "redis://b:6379"})
+
+	// index in h2 => index in h1
+	remap := []int{2, 0, 1}
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		i1, _ := h1.get(key)
+		i2, _ := h2.get(key)
+		if remap[i2] != i1 {
+			t.Errorf("Error: TestHashRingStableOrder key %s moved on reorder", key)
+			return
+		}
+	}
+}
+
+func TestHashRingMinimalMovement(t *testing.T) {
+	servers := []string{"redis://a:6379", "redis://b:6379", "redis://c:6379"}
+	h1 := testRing(servers)
+	h2 := testRing(append(servers, "redis://d:6379"))
+
+	numKeys, moved := 10000, 0
+	for i := 0; i < numKeys; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		i1, _ := h1.get(key)
+		i2, _ := h2.get(key)
+		if i1 != i2 {
+			if i2 != 3 {
+				t.Errorf("Error: TestHashRingMinimalMovement key %s moved between old servers", key)
+				return
+			}
+			moved++
+		}
+	}
+
+	// ideal movement is 1/4 of keys
+	if moved > numKeys/3 {
+		t.Errorf("Error: TestHashRingMinimalMovement moved %d of %d keys", moved, numKeys)
+	}
+}
+
+func TestClusterDefaultConnSelect(t *testing.T) {
+	cl, err := NewClusterBuilder().
+		SetServers([]string{"redis://a:6379", "redis://b:6379"}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		c, err := cl.GetConn(fmt.Sprintf("key:%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[c.(*conn).url] = true
+	}
+
+	if len(seen) != 2 {
+		t.Errorf("Error: TestClusterDefaultConnSelect keys should be sharded on all servers, got %v", seen)
+	}
+}
@@ -0,0 +1,172 @@
+package redisfarm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	defaultHealthFailures = 3
+
+	// pings time out after it or health check interval, whichever is less
+	defaultPingTimeout = time.Second
+)
+
+// HealthHandler : called when a server changes health state
+type HealthHandler func(url string, healthy bool)
+
+// healthChecker : pings every server of cluster on interval
+type healthChecker struct {
+	interval time.Duration
+	failures int // consecutive failed pings to mark server unhealthy
+	timeout  time.Duration
+	handler  HealthHandler
+
+	mu      sync.RWMutex
+	healthy []bool
+	failed  []int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newHealthChecker(n int) *healthChecker {
+	h := &healthChecker{
+		failures: defaultHealthFailures,
+		timeout:  defaultPingTimeout,
+		healthy:  make([]bool, n),
+		failed:   make([]int, n),
+		stop:     make(chan struct{}),
+	}
+	for i := range h.healthy {
+		h.healthy[i] = true
+	}
+	return h
+}
+
+// SetHealthCheck : ping every server on interval, a server is unhealthy after
+// failures consecutive failed pings and healthy again on first successful ping
+func (c *ClusterBuilder) SetHealthCheck(interval time.Duration, failures int) *ClusterBuilder {
+	c.c.healthInterval = interval
+	c.c.healthFailures = failures
+	return c
+}
+
+// SetHealthHandler : called on health state change of a server
+func (c *ClusterBuilder) SetHealthHandler(fn HealthHandler) *ClusterBuilder {
+	c.c.healthHandler = fn
+	return c
+}
+
+// isHealthy : health state of server at idx
+func (h *healthChecker) isHealthy(idx int) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.healthy[idx]
+}
+
+func (h *healthChecker) start(c *Cluster) {
+	if h.interval <= 0 {
+		return
+	}
+	if h.interval < h.timeout {
+		h.timeout = h.interval
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		t := time.NewTicker(h.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-t.C:
+				h.check(c)
+			}
+		}
+	}()
+}
+
+// close : stops checker, safe to call more than once
+func (h *healthChecker) close() {
+	h.stopOnce.Do(func() { close(h.stop) })
+	h.wg.Wait()
+}
+
+// check : ping every server in parallel and update its state
+func (h *healthChecker) check(c *Cluster) {
+	errs := make([]error, len(c.conns))
+	pinged := make([]*conn, len(c.conns))
+
+	var wg sync.WaitGroup
+	for i, v := range c.conns {
+		sc, ok := v.(*conn)
+		if !ok {
+			continue
+		}
+
+		pinged[i] = sc
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = h.ping(pinged[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, sc := range pinged {
+		if sc == nil {
+			continue
+		}
+		if changed, healthy := h.update(i, errs[i] == nil); changed && h.handler != nil {
+			h.handler(sc.url, healthy)
+		}
+	}
+}
+
+// update : returns true when state of server at idx changed
+func (h *healthChecker) update(idx int, ok bool) (bool, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ok {
+		h.failed[idx] = 0
+		if !h.healthy[idx] {
+			h.healthy[idx] = true
+			return true, true
+		}
+		return false, true
+	}
+
+	h.failed[idx]++
+	if h.healthy[idx] && h.failed[idx] >= h.failures {
+		h.healthy[idx] = false
+		return true, false
+	}
+	return false, h.healthy[idx]
+}
+
+// ping : on a new conn with timeouts, so that a full pool or a hung server
+// does not block the checker
+func (h *healthChecker) ping(c *conn) error {
+	rc, err := c.dial(
+		redis.DialConnectTimeout(h.timeout),
+		redis.DialReadTimeout(h.timeout),
+		redis.DialWriteTimeout(h.timeout),
+	)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = rc.Do("PING")
+	return err
+}
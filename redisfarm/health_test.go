@@ -0,0 +1,151 @@
+package redisfarm
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestHealthCheckerUpdate(t *testing.T) {
+	h := newHealthChecker(1)
+	h.failures = 2
+
+	if changed, _ := h.update(0, false); changed {
+		t.Errorf("Error: TestHealthCheckerUpdate server should be healthy before %d failures", h.failures)
+		return
+	}
+
+	if changed, healthy := h.update(0, false); !changed || healthy {
+		t.Errorf("Error: TestHealthCheckerUpdate server should be unhealthy after %d failures", h.failures)
+		return
+	}
+
+	if changed, healthy := h.update(0, true); !changed || !healthy {
+		t.Errorf("Error: TestHealthCheckerUpdate server should be healthy on recovery")
+	}
+}
+
+func TestClusterHealthCheck(t *testing.T) {
+	dead := "redis://localhost:1"
+
+	var mu sync.Mutex
+	events := map[string]bool{}
+
+	cl, err := NewClusterBuilder().
+		SetServers([]string{testServer, dead}).
+		SetHealthCheck(10*time.Millisecond, 2).
+		SetHealthHandler(func(url string, healthy bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			events[url] = healthy
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	for i := 0; i < 100 && cl.health.isHealthy(1); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	healthy, ok := events[dead]
+	mu.Unlock()
+	if !ok || healthy {
+		t.Errorf("Error: TestClusterHealthCheck handler should be called for %s", dead)
+		return
+	}
+
+	for i := 0; i < 100; i++ {
+		c, err := cl.GetConn(fmt.Sprintf("key:%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.(*conn).url == dead {
+			t.Errorf("Error: TestClusterHealthCheck key routed to unhealthy server")
+			return
+		}
+	}
+
+	conns, _ := cl.AllConn()
+	if len(conns) != 1 {
+		t.Errorf("Error: TestClusterHealthCheck Got %d conns, Want only healthy conn", len(conns))
+	}
+}
+
+func TestHealthCheckerPingTimeout(t *testing.T) {
+	// accepts conns but never replies
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	cl, err := NewClusterBuilder().SetServers([]string{"redis://" + l.Addr().String()}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	h := newHealthChecker(1)
+	h.timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	if err := h.ping(cl.conns[0].(*conn)); err == nil || time.Since(start) > time.Second {
+		t.Errorf("Error: TestHealthCheckerPingTimeout Got %v after %s, Want timeout after %s", err, time.Since(start), h.timeout)
+	}
+
+	if s := cl.Stats(); s[0].ActiveCount != 0 {
+		t.Errorf("Error: TestHealthCheckerPingTimeout Got %d pool conns, Want ping outside pool", s[0].ActiveCount)
+	}
+}
+
+func TestClusterCloseTwice(t *testing.T) {
+	cl, err := NewClusterBuilder().
+		SetServers([]string{testServer}).
+		SetHealthCheck(10*time.Millisecond, 2).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cl.Close()
+	cl.Close()
+}
+
+func TestWriteAllSkipsUnhealthy(t *testing.T) {
+	cl, err := NewClusterBuilder().
+		SetServers([]string{testServer, "redis://localhost:1"}).
+		SetHealthCheck(10*time.Millisecond, 1).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	for i := 0; i < 100 && cl.health.isHealthy(1); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f, _ := NewBuilder().SetCluster([]*Cluster{cl}).Build()
+	err = f.WriteAll(func(c redis.Conn) (interface{}, error) {
+		return c.Do("PING")
+	})
+	if err != nil {
+		t.Errorf("Error: TestWriteAllSkipsUnhealthy Got %v, Want %v", err, nil)
+	}
+}
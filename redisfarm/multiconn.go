@@ -3,15 +3,73 @@ package redisfarm
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/garyburd/redigo/redis"
 )
 
 var (
-	// ErrMultiConnBlank : ""
-	ErrMultiConnBlank = errors.New("")
+	// ErrMultiConnReceive : "Receive is not supported, use Pipeline"
+	ErrMultiConnReceive = errors.New("Cluster: Receive is not supported, use Pipeline")
 )
 
+// ConnError : error of a conn in multi conn
+type ConnError struct {
+	Index int    // position of conn in multi conn
+	URL   string // server url of conn
+	Err   error
+}
+
+// Error :
+func (e *ConnError) Error() string {
+	return fmt.Sprintf("conn %d (%s): %s", e.Index, e.URL, e.Err.Error())
+}
+
+// Unwrap : underlying error of conn
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError : errors of the conns which failed in multi conn.
+// Multi conn returns nil error when every conn succeeds
+type MultiError struct {
+	Errs []*ConnError
+}
+
+// Error :
+func (e *MultiError) Error() string {
+	errs := make([]string, 0, len(e.Errs))
+	for _, v := range e.Errs {
+		errs = append(errs, v.Error())
+	}
+	return strings.Join(errs, ", ")
+}
+
+// Unwrap : errors of all failed conns, used by errors.Is and errors.As
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, v := range e.Errs {
+		errs = append(errs, v)
+	}
+	return errs
+}
+
+func (e *MultiError) append(idx int, c redis.Conn, err error) *MultiError {
+	if e == nil {
+		e = &MultiError{}
+	}
+	e.Errs = append(e.Errs, &ConnError{Index: idx, URL: connURL(c), Err: err})
+	return e
+}
+
+// toError : nil *MultiError must be returned as nil error
+func (e *MultiError) toError() error {
+	if e == nil {
+		return nil
+	}
+	return e
+}
+
 type multiConn struct {
 	conns []redis.Conn
 }
@@ -34,75 +92,82 @@ func (m *multiConn) Build() *multiConn {
 
 // Close closes the connection.
 func (m *multiConn) Close() error {
-	err := ErrMultiConnBlank
+	var err *MultiError
 
-	for _, c := range m.conns {
-		e := c.Close()
-		if e != nil {
-			err = fmt.Errorf("%s, %s", e.Error(), err.Error())
+	for i, c := range m.conns {
+		if e := c.Close(); e != nil {
+			err = err.append(i, c, e)
 		}
 	}
-	return redis.Error(err.Error())
+	return err.toError()
 }
 
 // Err returns a non-nil value when the connection is not usable.
 func (m *multiConn) Err() error {
-	err := ErrMultiConnBlank
+	var err *MultiError
 
-	for _, c := range m.conns {
-		e := c.Err()
-		if e != nil {
-			err = fmt.Errorf("%s, %s", e.Error(), err.Error())
+	for i, c := range m.conns {
+		if e := c.Err(); e != nil {
+			err = err.append(i, c, e)
 		}
 	}
-	return redis.Error(err.Error())
+	return err.toError()
 }
 
 // Do sends a command to the server and returns the received reply.
 func (m *multiConn) Do(commandName string, args ...interface{}) (interface{}, error) {
 	replies := []interface{}{}
-	err := ErrMultiConnBlank
+	var err *MultiError
 
-	for _, c := range m.conns {
+	for i, c := range m.conns {
 		r, e := c.Do(commandName, args...)
 		if e != nil {
-			err = fmt.Errorf("%s %s", e.Error(), err.Error())
+			err = err.append(i, c, e)
 		}
 		if r != nil {
 			replies = append(replies, r)
 		}
 	}
 
-	return replies, redis.Error(err.Error())
+	return replies, err.toError()
 }
 
 // Send writes the command to the client's output buffer.
 func (m *multiConn) Send(commandName string, args ...interface{}) error {
-	err := ErrMultiConnBlank
+	var err *MultiError
 
-	for _, c := range m.conns {
-		e := c.Send(commandName, args...)
-		if e != nil {
-			err = fmt.Errorf("%s, %s", e.Error(), err.Error())
+	for i, c := range m.conns {
+		if e := c.Send(commandName, args...); e != nil {
+			err = err.append(i, c, e)
 		}
 	}
-	return redis.Error(err.Error())
+	return err.toError()
 }
 
 // Flush flushes the output buffer to the Redis server.
 func (m *multiConn) Flush() error {
-	err := ErrMultiConnBlank
+	var err *MultiError
 
-	for _, c := range m.conns {
-		e := c.Flush()
-		if e != nil {
-			err = fmt.Errorf("%s, %s", e.Error(), err.Error())
+	for i, c := range m.conns {
+		if e := c.Flush(); e != nil {
+			err = err.append(i, c, e)
 		}
 	}
-	return redis.Error(err.Error())
+	return err.toError()
 }
 
 // Receive receives a single reply from the Redis server
 func (m *multiConn) Receive() (reply interface{}, err error) {
-	return nil, fmt.Errorf("Cluster: Receive is not supported")
+	return nil, ErrMultiConnReceive
+}
+
+// connURL : server url of conn, blank for conns not created by redisfarm
+func connURL(c redis.Conn) string {
+	switch v := c.(type) {
+	case *conn:
+		return v.url
+	case *slotConn:
+		return v.node.url
+	}
+	return ""
 }
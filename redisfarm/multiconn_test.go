@@ -0,0 +1,75 @@
+package redisfarm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// testConn : redis.Conn which replies with err
+type testConn struct {
+	err error
+}
+
+func (c *testConn) Close() error { return c.err }
+func (c *testConn) Err() error   { return c.err }
+func (c *testConn) Do(string, ...interface{}) (interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return "OK", nil
+}
+func (c *testConn) Send(string, ...interface{}) error { return c.err }
+func (c *testConn) Flush() error                      { return c.err }
+func (c *testConn) Receive() (interface{}, error)     { return nil, c.err }
+
+func TestMultiConnNilError(t *testing.T) {
+	mc := (&multiConn{}).SetConn(&testConn{}).SetConn(&testConn{}).Build()
+
+	r, err := mc.Do("PING")
+	if err != nil {
+		t.Errorf("Error: TestMultiConnNilError Got %v, Want nil", err)
+		return
+	}
+	if len(r.([]interface{})) != 2 {
+		t.Errorf("Error: TestMultiConnNilError Got %v, Want reply of each conn", r)
+		return
+	}
+
+	if err := mc.Send("PING"); err != nil {
+		t.Errorf("Error: TestMultiConnNilError Got %v, Want nil", err)
+	}
+}
+
+func TestMultiConnError(t *testing.T) {
+	errDown := redis.Error("ERR down")
+	mc := (&multiConn{}).SetConn(&testConn{}).SetConn(&testConn{err: errDown}).Build()
+
+	_, err := mc.Do("PING")
+
+	var me *MultiError
+	if !errors.As(err, &me) || len(me.Errs) != 1 || me.Errs[0].Index != 1 {
+		t.Errorf("Error: TestMultiConnError Got %v, Want *MultiError of conn 1", err)
+		return
+	}
+
+	if !errors.Is(err, errDown) {
+		t.Errorf("Error: TestMultiConnError errors.Is should find underlying error in %v", err)
+		return
+	}
+
+	var re redis.Error
+	if !errors.As(err, &re) || re != errDown {
+		t.Errorf("Error: TestMultiConnError errors.As should find redis.Error in %v", err)
+	}
+}
+
+func TestMultiConnErrorURL(t *testing.T) {
+	var me *MultiError
+	me = me.append(0, &conn{url: "redis://a:6379"}, errors.New("down"))
+
+	if me.Errs[0].URL != "redis://a:6379" {
+		t.Errorf("Error: TestMultiConnErrorURL Got %s, Want %s", me.Errs[0].URL, "redis://a:6379")
+	}
+}
@@ -0,0 +1,87 @@
+package redisfarm
+
+import (
+	"errors"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	// ErrPipelineConn : "Conn can not be pinned"
+	ErrPipelineConn = errors.New("Conn can not be pinned")
+)
+
+// Pipeline : commands sent on one pooled connection of a shard.
+// Send, Flush and Receive share the socket, so replies of a batch can be
+// read back in order, and MULTI/EXEC transactions work on the shard
+type Pipeline interface {
+	// Send writes the command to the client's output buffer.
+	Send(commandName string, args ...interface{}) error
+
+	// Flush flushes the output buffer to the Redis server.
+	Flush() error
+
+	// Receive receives a single reply from the Redis server
+	Receive() (interface{}, error)
+
+	// Do flushes pending commands and returns reply of the command
+	Do(commandName string, args ...interface{}) (interface{}, error)
+
+	// Multi starts a transaction, following commands are queued till Exec
+	Multi() error
+
+	// Exec runs the transaction and returns replies of queued commands
+	Exec() ([]interface{}, error)
+}
+
+// PipelineHandler : func run on pipeline of a shard
+type PipelineHandler func(Pipeline) error
+
+type pipeline struct {
+	redis.Conn
+}
+
+// Multi :
+func (p *pipeline) Multi() error {
+	return p.Send("MULTI")
+}
+
+// Exec :
+func (p *pipeline) Exec() ([]interface{}, error) {
+	return redis.Values(p.Do("EXEC"))
+}
+
+// Pipeline : runs fn on a pinned connection of the shard owning keyspace.
+// Connection is returned to pool when fn returns
+func (c *Cluster) Pipeline(keyspace string, fn PipelineHandler) error {
+	sc, err := c.GetConn(keyspace)
+	if err != nil {
+		return err
+	}
+
+	var v *conn
+	switch t := sc.(type) {
+	case *conn:
+		v = t
+	case *slotConn:
+		// redirects are not followed in pipeline
+		v = t.node
+	default:
+		return ErrPipelineConn
+	}
+
+	pc := v.pool.Get()
+	defer pc.Close()
+
+	return fn(&pipeline{Conn: pc})
+}
+
+// Pipeline : runs fn on a pinned connection of the shard owning keyspace in
+// every cluster. fn is called once per cluster and succeeds when WriteQuorum
+// clusters return nil
+func (f *Farm) Pipeline(keyspace string, fn PipelineHandler) error {
+	_, err := f.write(func(cl *Cluster) (interface{}, error) {
+		return nil, cl.Pipeline(keyspace, fn)
+	})
+	return err
+}
@@ -0,0 +1,89 @@
+package redisfarm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	testServer = "redis://localhost:6379"
+)
+
+func testPipelineFarm(t *testing.T) *Farm {
+	cl, err := NewClusterBuilder().SetServers([]string{testServer}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewBuilder().SetCluster([]*Cluster{cl}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestPipeline(t *testing.T) {
+	f := testPipelineFarm(t)
+	numCmd := 10
+
+	var replies []interface{}
+	err := f.Pipeline("pipeline", func(p Pipeline) error {
+		for i := 0; i < numCmd; i++ {
+			if err := p.Send("SET", fmt.Sprintf("pipeline:%d", i), i); err != nil {
+				return err
+			}
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+
+		for i := 0; i < numCmd; i++ {
+			r, err := p.Receive()
+			if err != nil {
+				return err
+			}
+			replies = append(replies, r)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(replies) != numCmd {
+		t.Errorf("Error: TestPipeline Got %d replies, Want %d", len(replies), numCmd)
+	}
+}
+
+func TestPipelineMultiExec(t *testing.T) {
+	f := testPipelineFarm(t)
+
+	var replies []interface{}
+	err := f.Pipeline("pipeline", func(p Pipeline) error {
+		p.Multi()
+		p.Send("SET", "pipeline:tx", "1")
+		p.Send("INCR", "pipeline:tx")
+
+		r, err := p.Exec()
+		replies = r
+		return err
+	})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(replies) != 2 {
+		t.Errorf("Error: TestPipelineMultiExec Got %v, Want reply of 2 commands", replies)
+		return
+	}
+
+	if n, _ := redis.Int(replies[1], nil); n != 2 {
+		t.Errorf("Error: TestPipelineMultiExec Got %d, Want %d", n, 2)
+	}
+}
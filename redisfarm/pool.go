@@ -0,0 +1,137 @@
+package redisfarm
+
+import (
+	"crypto/tls"
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	// ErrConnExpired : "Conn is older than max conn lifetime"
+	ErrConnExpired = errors.New("Conn is older than max conn lifetime")
+
+	defaultIdleConns   = 6
+	defaultIdleTimeout = 240 * time.Second
+)
+
+// poolConfig : pool and dial settings shared by every server of a cluster
+type poolConfig struct {
+	maxIdle         int
+	maxActive       int // defaults to maxIdle
+	idleTimeout     time.Duration
+	maxConnLifetime time.Duration // 0 means conns never expire
+	dialTimeout     time.Duration
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	tlsConfig       *tls.Config // used for rediss:// servers
+	password        string      // password in server url takes precedence
+}
+
+// ServerStats : pool stats of a server
+type ServerStats struct {
+	URL         string
+	ActiveCount int // conns in use and idle
+	IdleCount   int
+}
+
+// lifetimeConn : conn with its creation time, to expire old conns
+type lifetimeConn struct {
+	redis.Conn
+	created time.Time
+}
+
+// DoWithTimeout : forwarded, so that pooled conns still support redis.DoWithTimeout
+func (c *lifetimeConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+// ReceiveWithTimeout : forwarded, so that pooled conns still support redis.ReceiveWithTimeout
+func (c *lifetimeConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func newPoolConfig() *poolConfig {
+	return &poolConfig{
+		maxIdle:     defaultIdleConns,
+		idleTimeout: defaultIdleTimeout,
+	}
+}
+
+func (p *poolConfig) dialOptions() []redis.DialOption {
+	var opts []redis.DialOption
+
+	if p.dialTimeout > 0 {
+		opts = append(opts, redis.DialConnectTimeout(p.dialTimeout))
+	}
+	if p.readTimeout > 0 {
+		opts = append(opts, redis.DialReadTimeout(p.readTimeout))
+	}
+	if p.writeTimeout > 0 {
+		opts = append(opts, redis.DialWriteTimeout(p.writeTimeout))
+	}
+	if p.tlsConfig != nil {
+		opts = append(opts, redis.DialTLSConfig(p.tlsConfig))
+	}
+	if p.password != "" {
+		opts = append(opts, redis.DialPassword(p.password))
+	}
+	return opts
+}
+
+// create conn pool
+func newpool(c *conn) *redis.Pool {
+	cfg := c.cfg
+
+	maxActive := cfg.maxActive
+	if maxActive == 0 {
+		maxActive = cfg.maxIdle
+	}
+
+	return &redis.Pool{
+		MaxIdle:     cfg.maxIdle,
+		MaxActive:   maxActive,
+		Wait:        true,
+		IdleTimeout: cfg.idleTimeout,
+
+		Dial: func() (redis.Conn, error) {
+			rc, err := c.dial()
+			if err != nil {
+				return nil, err
+			}
+			return &lifetimeConn{Conn: rc, created: time.Now()}, nil
+		},
+
+		TestOnBorrow: func(rc redis.Conn, t time.Time) error {
+			if lc, ok := rc.(*lifetimeConn); ok && cfg.maxConnLifetime > 0 && time.Since(lc.created) > cfg.maxConnLifetime {
+				return ErrConnExpired
+			}
+
+			_, err := rc.Do("PING")
+			return err
+		},
+	}
+}
+
+// Stats : pool stats of every server in cluster
+func (c *Cluster) Stats() []ServerStats {
+	conns := c.serverConns()
+	stats := make([]ServerStats, 0, len(conns))
+
+	for _, v := range conns {
+		s := v.pool.Stats()
+		stats = append(stats, ServerStats{URL: v.url, ActiveCount: s.ActiveCount, IdleCount: s.IdleCount})
+	}
+	return stats
+}
+
+// Stats : pool stats of every cluster in farm, indexed by cluster
+func (f *Farm) Stats() [][]ServerStats {
+	stats := make([][]ServerStats, 0, len(f.clusters))
+
+	for _, v := range f.clusters {
+		stats = append(stats, v.Stats())
+	}
+	return stats
+}
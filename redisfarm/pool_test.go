@@ -0,0 +1,80 @@
+package redisfarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestClusterPoolConfig(t *testing.T) {
+	cl, err := NewClusterBuilder().
+		SetServers([]string{testServer}).
+		SetMaxIdleConns(2).
+		SetMaxActiveConns(4).
+		SetIdleTimeout(time.Minute).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := cl.conns[0].(*conn).pool
+	if p.MaxIdle != 2 || p.MaxActive != 4 || p.IdleTimeout != time.Minute {
+		t.Errorf("Error: TestClusterPoolConfig Got %d %d %s, Want %d %d %s", p.MaxIdle, p.MaxActive, p.IdleTimeout, 2, 4, time.Minute)
+	}
+}
+
+func TestClusterMaxConnLifetime(t *testing.T) {
+	cl, err := NewClusterBuilder().
+		SetServers([]string{testServer}).
+		SetMaxConnLifetime(time.Nanosecond).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := cl.conns[0].(*conn).pool
+	c, err := p.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := p.TestOnBorrow(c, time.Now()); err != ErrConnExpired {
+		t.Errorf("Error: TestClusterMaxConnLifetime Got %v, Want %v", err, ErrConnExpired)
+	}
+}
+
+func TestPooledConnWithTimeout(t *testing.T) {
+	cl, err := NewClusterBuilder().SetServers([]string{testServer}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cl.conns[0].(*conn).pool.Get()
+	defer c.Close()
+
+	if r, err := redis.String(redis.DoWithTimeout(c, time.Second, "PING")); err != nil || r != "PONG" {
+		t.Errorf("Error: TestPooledConnWithTimeout Got %v %v, Want PONG", r, err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	f := testPipelineFarm(t)
+
+	err := f.Pipeline("stats", func(p Pipeline) error {
+		s := f.Stats()
+		if len(s) != 1 || len(s[0]) != 1 || s[0][0].ActiveCount != 1 || s[0][0].URL != testServer {
+			t.Errorf("Error: TestStats Got %v, Want 1 active conn on %s", s, testServer)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s := f.Stats(); s[0][0].IdleCount != 1 {
+		t.Errorf("Error: TestStats Got %v, Want 1 idle conn after pipeline", s)
+	}
+}
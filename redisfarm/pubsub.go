@@ -0,0 +1,202 @@
+package redisfarm
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	defaultSubscribeMinBackoff = 100 * time.Millisecond
+	defaultSubscribeMaxBackoff = 30 * time.Second
+	defaultSubscribeBuffer     = 100
+)
+
+// Message : message received on a subscription
+type Message struct {
+	Channel string
+	Pattern string // matched pattern, set only for PSubscribe
+	Data    []byte
+}
+
+type subscriber struct {
+	farm       *Farm
+	channels   []interface{}
+	patterns   []interface{}
+	out        chan Message
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Publish : publish data on channel.
+// Message is sent to the server owning channel in every cluster
+func (f *Farm) Publish(channel string, data interface{}) error {
+	_, err := f.Write(channel, func(conn redis.Conn) (interface{}, error) {
+		return conn.Do("PUBLISH", channel, data)
+	})
+	return err
+}
+
+// Subscribe : subscribe to channels.
+// Returned chan is closed when ctx is cancelled
+func (f *Farm) Subscribe(ctx context.Context, channels ...string) <-chan Message {
+	return f.subscribe(ctx, channels, nil)
+}
+
+// PSubscribe : subscribe to channels matching patterns.
+// Returned chan is closed when ctx is cancelled
+func (f *Farm) PSubscribe(ctx context.Context, patterns ...string) <-chan Message {
+	return f.subscribe(ctx, nil, patterns)
+}
+
+func (f *Farm) subscribe(ctx context.Context, channels, patterns []string) <-chan Message {
+	s := &subscriber{
+		farm:       f,
+		channels:   toArgs(channels),
+		patterns:   toArgs(patterns),
+		out:        make(chan Message, defaultSubscribeBuffer),
+		minBackoff: defaultSubscribeMinBackoff,
+		maxBackoff: defaultSubscribeMaxBackoff,
+	}
+
+	go s.run(ctx)
+	return s.out
+}
+
+// run : listens on a cluster till ctx is done.
+// On error it reconnects with exponential backoff, moving to next cluster of farm
+func (s *subscriber) run(ctx context.Context) {
+	defer close(s.out)
+
+	backoff := s.minBackoff
+	for i := 0; ; i = (i + 1) % len(s.farm.clusters) {
+		subscribed, err := s.listen(ctx, s.farm.clusters[i])
+		if ctx.Err() != nil {
+			return
+		}
+
+		if subscribed {
+			backoff = s.minBackoff
+		}
+		log.Printf("Error: Subscriber conn lost, reconnecting in %s: %s", backoff, err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// listen : subscribe on servers of cluster. Returns on first error or when ctx is done
+func (s *subscriber) listen(ctx context.Context, cl *Cluster) (bool, error) {
+	var pscs []redis.PubSubConn
+
+	closeAll := func() {
+		for _, v := range pscs {
+			v.Close()
+		}
+	}
+
+	for _, c := range cl.subscribeConns() {
+		// subscriber blocks on read, so read timeout of pool is not used
+		rc, err := c.dial(redis.DialReadTimeout(0))
+		if err != nil {
+			closeAll()
+			return false, err
+		}
+
+		psc := redis.PubSubConn{Conn: rc}
+		pscs = append(pscs, psc)
+
+		if err := s.sub(psc); err != nil {
+			closeAll()
+			return false, err
+		}
+	}
+
+	errc := make(chan error, len(pscs))
+	wg := sync.WaitGroup{}
+
+	for _, v := range pscs {
+		wg.Add(1)
+		go func(psc redis.PubSubConn) {
+			defer wg.Done()
+			errc <- s.receive(ctx, psc)
+		}(v)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errc:
+	}
+
+	// closing conns unblocks the receivers
+	closeAll()
+	wg.Wait()
+
+	return true, err
+}
+
+// subscribeConns : every server since a publisher writes to the server owning
+// the channel. Redis Cluster broadcasts messages, so one node is enough
+func (c *Cluster) subscribeConns() []*conn {
+	conns := c.serverConns()
+	if c.slots != nil && len(conns) > 1 {
+		return conns[:1]
+	}
+	return conns
+}
+
+func (s *subscriber) sub(psc redis.PubSubConn) error {
+	if len(s.channels) > 0 {
+		if err := psc.Subscribe(s.channels...); err != nil {
+			return err
+		}
+	}
+	if len(s.patterns) > 0 {
+		if err := psc.PSubscribe(s.patterns...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *subscriber) receive(ctx context.Context, psc redis.PubSubConn) error {
+	for {
+		var m Message
+
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			m = Message{Channel: v.Channel, Data: v.Data}
+		case redis.PMessage:
+			m = Message{Channel: v.Channel, Pattern: v.Pattern, Data: v.Data}
+		case error:
+			return v
+		default:
+			continue
+		}
+
+		select {
+		case s.out <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func toArgs(arr []string) []interface{} {
+	args := make([]interface{}, 0, len(arr))
+	for _, v := range arr {
+		args = append(args, v)
+	}
+	return args
+}
@@ -0,0 +1,85 @@
+package redisfarm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testReceive : publish till message is received, subscription is async
+func testReceive(t *testing.T, f *Farm, ch <-chan Message, channel string) (Message, bool) {
+	tick := time.NewTicker(20 * time.Millisecond)
+	defer tick.Stop()
+	timeout := time.After(2 * time.Second)
+
+	for {
+		select {
+		case m := <-ch:
+			return m, true
+		case <-tick.C:
+			if err := f.Publish(channel, "hello"); err != nil {
+				t.Error(err)
+				return Message{}, false
+			}
+		case <-timeout:
+			return Message{}, false
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	f := testPipelineFarm(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := f.Subscribe(ctx, "pubsub:test")
+
+	m, ok := testReceive(t, f, ch, "pubsub:test")
+	if !ok {
+		t.Errorf("Error: TestSubscribe message not received")
+		return
+	}
+
+	if m.Channel != "pubsub:test" || string(m.Data) != "hello" {
+		t.Errorf("Error: TestSubscribe Got %v, Want message on %s", m, "pubsub:test")
+	}
+}
+
+func TestPSubscribe(t *testing.T) {
+	f := testPipelineFarm(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := f.PSubscribe(ctx, "pubsub:*")
+
+	m, ok := testReceive(t, f, ch, "pubsub:pattern")
+	if !ok {
+		t.Errorf("Error: TestPSubscribe message not received")
+		return
+	}
+
+	if m.Pattern != "pubsub:*" || m.Channel != "pubsub:pattern" {
+		t.Errorf("Error: TestPSubscribe Got %v, Want message on pattern %s", m, "pubsub:*")
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	// unreachable server keeps subscriber in reconnect loop
+	cl, _ := NewClusterBuilder().SetServers([]string{"redis://localhost:1"}).Build()
+	f, _ := NewBuilder().SetCluster([]*Cluster{cl}).Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := f.Subscribe(ctx, "pubsub:test")
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Error: TestSubscribeCancel no message expected")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Error: TestSubscribeCancel chan should be closed on cancel")
+	}
+}
@@ -0,0 +1,130 @@
+package redisfarm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Exec : func run on conn of a cluster
+type Exec func(redis.Conn) (interface{}, error)
+
+// ClusterError : error returned by a cluster of farm
+type ClusterError struct {
+	Cluster int
+	Err     error
+}
+
+// Error :
+func (e *ClusterError) Error() string {
+	return fmt.Sprintf("cluster %d: %s", e.Cluster, e.Err.Error())
+}
+
+// Unwrap : underlying error of cluster
+func (e *ClusterError) Unwrap() error {
+	return e.Err
+}
+
+// QuorumError : returned when less than quorum clusters served the command
+type QuorumError struct {
+	Quorum int
+	Acks   int
+	Errs   []*ClusterError
+}
+
+// Error :
+func (e *QuorumError) Error() string {
+	errs := make([]string, 0, len(e.Errs))
+	for _, v := range e.Errs {
+		errs = append(errs, v.Error())
+	}
+	return fmt.Sprintf("redisfarm: %d of %d acks needed: %s", e.Acks, e.Quorum, strings.Join(errs, ", "))
+}
+
+// Unwrap : errors of all failed clusters, used by errors.Is and errors.As
+func (e *QuorumError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, v := range e.Errs {
+		errs = append(errs, v)
+	}
+	return errs
+}
+
+// Write : runs fn on keyspace conn of every cluster.
+// Succeeds with reply of first cluster that acked when WriteQuorum clusters ack
+func (f *Farm) Write(keyspace string, fn Exec) (interface{}, error) {
+	return f.write(func(cl *Cluster) (interface{}, error) {
+		return clusterExec(cl, keyspace, fn)
+	})
+}
+
+// Read : runs fn on keyspace conn of clusters in order.
+// Returns reply of first cluster that succeeds, falling back to next one on error
+func (f *Farm) Read(keyspace string, fn Exec) (interface{}, error) {
+	return f.read(func(cl *Cluster) (interface{}, error) {
+		return clusterExec(cl, keyspace, fn)
+	})
+}
+
+// ReadCluster : runs fn on keyspace conn of cluster at idx, without fallback.
+// For data which lives on the cluster it was written to
+func (f *Farm) ReadCluster(idx int, keyspace string, fn Exec) (interface{}, error) {
+	if idx < 0 || idx >= len(f.clusters) {
+		return nil, ErrFarmNoCluster
+	}
+	return clusterExec(f.clusters[idx], keyspace, fn)
+}
+
+// NumClusters : number of clusters of farm
+func (f *Farm) NumClusters() int {
+	return len(f.clusters)
+}
+
+// write : runs fn on every cluster, needs writeQuorum acks
+func (f *Farm) write(fn func(*Cluster) (interface{}, error)) (interface{}, error) {
+	var reply interface{}
+	qe := &QuorumError{Quorum: f.writeQuorum}
+
+	for i, v := range f.clusters {
+		r, err := fn(v)
+		if err != nil {
+			qe.Errs = append(qe.Errs, &ClusterError{Cluster: i, Err: err})
+			continue
+		}
+
+		if qe.Acks == 0 {
+			reply = r
+		}
+		qe.Acks++
+	}
+
+	if qe.Acks < qe.Quorum {
+		return nil, qe
+	}
+	return reply, nil
+}
+
+// read : runs fn on clusters in order till one succeeds
+func (f *Farm) read(fn func(*Cluster) (interface{}, error)) (interface{}, error) {
+	qe := &QuorumError{Quorum: 1}
+
+	for i, v := range f.clusters {
+		r, err := fn(v)
+		if err != nil {
+			qe.Errs = append(qe.Errs, &ClusterError{Cluster: i, Err: err})
+			continue
+		}
+		return r, nil
+	}
+
+	return nil, qe
+}
+
+func clusterExec(cl *Cluster, keyspace string, fn Exec) (interface{}, error) {
+	conn, err := cl.GetConn(keyspace)
+	if err != nil {
+		return nil, err
+	}
+	return fn(conn)
+}
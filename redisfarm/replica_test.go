@@ -0,0 +1,104 @@
+package redisfarm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func testReplicaFarm(t *testing.T, quorum int, servers ...string) *Farm {
+	var clusters []*Cluster
+	for _, s := range servers {
+		cl, err := NewClusterBuilder().SetServers([]string{s}).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		clusters = append(clusters, cl)
+	}
+
+	f, err := NewBuilder().SetCluster(clusters).SetWriteQuorum(quorum).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// testReplicaExec : fails on conn whose url is in down
+func testReplicaExec(down ...string) Exec {
+	return func(c redis.Conn) (interface{}, error) {
+		url := c.(*conn).url
+		for _, v := range down {
+			if v == url {
+				return nil, errors.New("down")
+			}
+		}
+		return url, nil
+	}
+}
+
+func TestFarmInvalidQuorum(t *testing.T) {
+	cl, _ := NewClusterBuilder().SetServers([]string{"redis://a:6379"}).Build()
+
+	_, err := NewBuilder().SetCluster([]*Cluster{cl}).SetWriteQuorum(2).Build()
+	if err != ErrFarmInvalidQuorum {
+		t.Errorf("Error: TestFarmInvalidQuorum Got %v, Want %v", err, ErrFarmInvalidQuorum)
+	}
+}
+
+func TestFarmWriteQuorum(t *testing.T) {
+	f := testReplicaFarm(t, 2, "redis://a:6379", "redis://b:6379", "redis://c:6379")
+
+	r, err := f.Write("key", testReplicaExec("redis://a:6379"))
+	if err != nil {
+		t.Errorf("Error: TestFarmWriteQuorum write should succeed with one cluster down: %v", err)
+		return
+	}
+	if r != "redis://b:6379" {
+		t.Errorf("Error: TestFarmWriteQuorum Got %v, Want reply of first acked cluster", r)
+		return
+	}
+
+	_, err = f.Write("key", testReplicaExec("redis://a:6379", "redis://c:6379"))
+	qe, ok := err.(*QuorumError)
+	if !ok {
+		t.Errorf("Error: TestFarmWriteQuorum Got %v, Want *QuorumError", err)
+		return
+	}
+	if qe.Acks != 1 || qe.Quorum != 2 || len(qe.Errs) != 2 || qe.Errs[0].Cluster != 0 || qe.Errs[1].Cluster != 2 {
+		t.Errorf("Error: TestFarmWriteQuorum unexpected error %v", qe)
+	}
+}
+
+func TestFarmReadFallback(t *testing.T) {
+	f := testReplicaFarm(t, 0, "redis://a:6379", "redis://b:6379")
+
+	r, err := f.Read("key", testReplicaExec("redis://a:6379"))
+	if err != nil || r != "redis://b:6379" {
+		t.Errorf("Error: TestFarmReadFallback Got %v %v, Want reply of second cluster", r, err)
+		return
+	}
+
+	_, err = f.Read("key", testReplicaExec("redis://a:6379", "redis://b:6379"))
+	if qe, ok := err.(*QuorumError); !ok || len(qe.Errs) != 2 {
+		t.Errorf("Error: TestFarmReadFallback Got %v, Want *QuorumError of both clusters", err)
+	}
+}
+
+func TestFarmReadCluster(t *testing.T) {
+	f := testReplicaFarm(t, 0, "redis://a:6379", "redis://b:6379")
+
+	r, err := f.ReadCluster(1, "key", testReplicaExec())
+	if err != nil || r != "redis://b:6379" {
+		t.Errorf("Error: TestFarmReadCluster Got %v %v, Want reply of second cluster", r, err)
+	}
+
+	// no fallback to another cluster
+	if _, err := f.ReadCluster(0, "key", testReplicaExec("redis://a:6379")); err == nil {
+		t.Errorf("Error: TestFarmReadCluster Got %v, Want error of first cluster", err)
+	}
+
+	if _, err := f.ReadCluster(2, "key", testReplicaExec()); err != ErrFarmNoCluster {
+		t.Errorf("Error: TestFarmReadCluster Got %v, Want %v", err, ErrFarmNoCluster)
+	}
+}
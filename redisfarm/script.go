@@ -0,0 +1,42 @@
+package redisfarm
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// DoScript : run script on conn, loading it when the server does not have it.
+// Script.Do only falls back to EVAL for a plain redis.Error, so NOSCRIPT
+// wrapped in a multi conn error is handled here
+func DoScript(conn redis.Conn, s *redis.Script, args ...interface{}) (interface{}, error) {
+	reply, err := s.Do(conn, args...)
+	if !isNoScript(err) {
+		return reply, err
+	}
+
+	if err := s.Load(conn); err != nil {
+		return nil, err
+	}
+	return s.Do(conn, args...)
+}
+
+// LoadScripts : load scripts on every healthy server of every cluster, so that
+// a bad script or an unreachable farm is reported early. A server which
+// misses them loads them on first DoScript
+func (f *Farm) LoadScripts(scripts map[string]*redis.Script) error {
+	return f.WriteAll(func(conn redis.Conn) (interface{}, error) {
+		for _, v := range scripts {
+			if err := v.Load(conn); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+}
+
+func isNoScript(err error) bool {
+	var e redis.Error
+	return errors.As(err, &e) && strings.HasPrefix(string(e), "NOSCRIPT ")
+}
@@ -0,0 +1,139 @@
+package redisfarm
+
+import (
+	"sync"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// ShardExec : func run on conn of a shard with positions of the keys it owns
+type ShardExec func(conn redis.Conn, idx []int) error
+
+// shard : keys of a multi key command owned by one server
+type shard struct {
+	conn redis.Conn
+	idx  []int
+}
+
+// WriteKeys : groups keys by shard in every cluster and runs fn once per shard,
+// shards in parallel. Succeeds when WriteQuorum clusters ack every shard
+func (f *Farm) WriteKeys(keys []string, fn ShardExec) error {
+	_, err := f.write(func(cl *Cluster) (interface{}, error) {
+		return nil, cl.execShards(keys, fn)
+	})
+	return err
+}
+
+// ReadKeys : groups keys by shard and runs fn once per shard, shards in parallel.
+// Falls back to next cluster when a shard fails
+func (f *Farm) ReadKeys(keys []string, fn ShardExec) error {
+	_, err := f.read(func(cl *Cluster) (interface{}, error) {
+		return nil, cl.execShards(keys, fn)
+	})
+	return err
+}
+
+// WriteAll : runs fn on every healthy server of every cluster, masters in
+// cluster mode, servers in parallel. Succeeds when WriteQuorum clusters ack
+// every healthy server, so that a dead server does not fail the write
+func (f *Farm) WriteAll(fn Exec) error {
+	_, err := f.write(func(cl *Cluster) (interface{}, error) {
+		return nil, cl.execAll(fn)
+	})
+	return err
+}
+
+// execAll : runs fn on every healthy server in parallel
+func (c *Cluster) execAll(fn Exec) error {
+	conns, err := c.AllConn()
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var merr *MultiError
+	wg := sync.WaitGroup{}
+
+	for i, v := range conns {
+		wg.Add(1)
+		go func(i int, conn redis.Conn) {
+			defer wg.Done()
+
+			if _, err := fn(conn); err != nil {
+				mu.Lock()
+				merr = merr.append(i, conn, err)
+				mu.Unlock()
+			}
+		}(i, v)
+	}
+	wg.Wait()
+
+	return merr.toError()
+}
+
+// execShards : runs fn on every shard owning keys in parallel
+func (c *Cluster) execShards(keys []string, fn ShardExec) error {
+	shards, err := c.shards(keys)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var merr *MultiError
+	wg := sync.WaitGroup{}
+
+	for i, v := range shards {
+		wg.Add(1)
+		go func(i int, s *shard) {
+			defer wg.Done()
+
+			if err := fn(s.conn, s.idx); err != nil {
+				mu.Lock()
+				merr = merr.append(i, s.conn, err)
+				mu.Unlock()
+			}
+		}(i, v)
+	}
+	wg.Wait()
+
+	return merr.toError()
+}
+
+// shards : keys grouped by the server owning them, in order of first key
+func (c *Cluster) shards(keys []string) ([]*shard, error) {
+	var shards []*shard
+	byOwner := map[interface{}]*shard{}
+
+	for i, k := range keys {
+		owner, conn, err := c.owner(k)
+		if err != nil {
+			return nil, err
+		}
+
+		s, ok := byOwner[owner]
+		if !ok {
+			s = &shard{conn: conn}
+			byOwner[owner] = s
+			shards = append(shards, s)
+		}
+		s.idx = append(s.idx, i)
+	}
+	return shards, nil
+}
+
+// owner : id and conn of server owning keyspace, node address in cluster mode
+func (c *Cluster) owner(keyspace string) (interface{}, redis.Conn, error) {
+	if c.slots != nil {
+		conn, err := c.slots.getConn(keyspace)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nodeAddr(conn.(*slotConn).node), conn, nil
+	}
+
+	idx, err := c.CS(keyspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	return idx, c.conns[idx], nil
+}
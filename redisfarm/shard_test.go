@@ -0,0 +1,100 @@
+package redisfarm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestReadKeysGroupsByShard(t *testing.T) {
+	cl, err := NewClusterBuilder().SetServers([]string{"redis://a:6379", "redis://b:6379", "redis://c:6379"}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := NewBuilder().SetCluster([]*Cluster{cl}).Build()
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("key%d", i))
+	}
+
+	var mu sync.Mutex
+	calls := 0
+	seen := make([]int, len(keys))
+
+	err = f.ReadKeys(keys, func(c redis.Conn, idx []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+
+		for _, i := range idx {
+			seen[i]++
+			if want, _ := cl.GetConn(keys[i]); want != c {
+				t.Errorf("Error: TestReadKeysGroupsByShard key %s on %s, Want %s", keys[i], connURL(c), connURL(want))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if calls != 3 {
+		t.Errorf("Error: TestReadKeysGroupsByShard Got %d shard calls, Want %d", calls, 3)
+	}
+	for i, v := range seen {
+		if v != 1 {
+			t.Errorf("Error: TestReadKeysGroupsByShard key %s seen %d times, Want once", keys[i], v)
+		}
+	}
+}
+
+func TestWriteKeysQuorum(t *testing.T) {
+	f := testReplicaFarm(t, 2, "redis://a:6379", "redis://b:6379")
+	down := errors.New("down")
+
+	fail := func(url string) ShardExec {
+		return func(c redis.Conn, idx []int) error {
+			if connURL(c) == url {
+				return down
+			}
+			return nil
+		}
+	}
+
+	err := f.WriteKeys([]string{"k1", "k2"}, fail("redis://b:6379"))
+	if !errors.Is(err, down) {
+		t.Errorf("Error: TestWriteKeysQuorum Got %v, Want %v", err, down)
+		return
+	}
+
+	f = testReplicaFarm(t, 1, "redis://a:6379", "redis://b:6379")
+	if err := f.WriteKeys([]string{"k1", "k2"}, fail("redis://b:6379")); err != nil {
+		t.Errorf("Error: TestWriteKeysQuorum Got %v, Want nil", err)
+	}
+}
+
+func TestWriteAll(t *testing.T) {
+	cl, err := NewClusterBuilder().SetServers([]string{"redis://a:6379", "redis://b:6379"}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := NewBuilder().SetCluster([]*Cluster{cl}).Build()
+
+	var mu sync.Mutex
+	seen := map[string]bool{}
+
+	err = f.WriteAll(func(c redis.Conn) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[connURL(c)] = true
+		return nil, nil
+	})
+	if err != nil || len(seen) != 2 {
+		t.Errorf("Error: TestWriteAll Got %v %v, Want every server", seen, err)
+	}
+}
@@ -0,0 +1,358 @@
+package redisfarm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	clusterSlots = 16384
+)
+
+var (
+	// ErrClusterSlotNotCovered : "Slot is not served by any node"
+	ErrClusterSlotNotCovered = errors.New("Slot is not served by any node")
+
+	// ErrClusterTooManyRedirects : "Too many MOVED/ASK redirects"
+	ErrClusterTooManyRedirects = errors.New("Too many MOVED/ASK redirects")
+
+	defaultMaxRedirects = 5
+)
+
+// slotRouter : routes keys to the master owning its hash slot in a native
+// Redis Cluster. Slot map is loaded with CLUSTER SLOTS and refreshed on MOVED
+type slotRouter struct {
+	cfg    *poolConfig
+	scheme string
+	user   *url.Userinfo
+
+	mu    sync.RWMutex
+	slots [clusterSlots]string // slot => master addr
+	nodes map[string]*conn     // addr => conn
+
+	refreshing int32
+}
+
+// slotConn : conn of a slot, follows MOVED/ASK redirects
+type slotConn struct {
+	router *slotRouter
+	node   *conn
+}
+
+// redirect : MOVED or ASK error reply
+type redirect struct {
+	ask  bool
+	slot int
+	addr string
+}
+
+// SetClusterMode : servers are seed nodes of a native Redis Cluster.
+// Keys are routed by hash slot, conn select handler is not used and Build
+// fails with ErrClusterModeHealthCheck when health check is set
+func (c *ClusterBuilder) SetClusterMode(enabled bool) *ClusterBuilder {
+	c.c.clusterMode = enabled
+	return c
+}
+
+func newSlotRouter(seeds []string, cfg *poolConfig) (*slotRouter, error) {
+	u, err := url.Parse(seeds[0])
+	if err != nil {
+		return nil, err
+	}
+
+	r := &slotRouter{
+		cfg:    cfg,
+		scheme: u.Scheme,
+		user:   u.User,
+		nodes:  make(map[string]*conn),
+	}
+
+	for _, v := range seeds {
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		r.nodes[u.Host] = createConn(v, cfg).(*conn)
+	}
+
+	return r, r.refresh()
+}
+
+// refresh : load slot map from the first node that answers CLUSTER SLOTS
+func (r *slotRouter) refresh() error {
+	var err error
+
+	for _, v := range r.nodeList() {
+		var reply interface{}
+		if reply, err = v.Do("CLUSTER", "SLOTS"); err != nil {
+			continue
+		}
+		if err = r.load(v, reply); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// refreshAsync : refresh in background, one refresh at a time
+func (r *slotRouter) refreshAsync() {
+	if !atomic.CompareAndSwapInt32(&r.refreshing, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&r.refreshing, 0)
+		r.refresh()
+	}()
+}
+
+// load : parse CLUSTER SLOTS reply of node, which is
+// [[start, end, [ip, port, id], [replica ip, port, id]...]...]
+func (r *slotRouter) load(node *conn, reply interface{}) error {
+	ranges, err := redis.Values(reply, nil)
+	if err != nil {
+		return err
+	}
+
+	var slots [clusterSlots]string
+	for _, v := range ranges {
+		rng, err := redis.Values(v, nil)
+		if err != nil || len(rng) < 3 {
+			return fmt.Errorf("invalid CLUSTER SLOTS reply: %v", v)
+		}
+
+		start, _ := redis.Int(rng[0], nil)
+		end, _ := redis.Int(rng[1], nil)
+		master, err := redis.Values(rng[2], nil)
+		if err != nil || len(master) < 2 || start < 0 || end >= clusterSlots {
+			return fmt.Errorf("invalid CLUSTER SLOTS reply: %v", v)
+		}
+
+		ip, _ := redis.String(master[0], nil)
+		port, _ := redis.Int(master[1], nil)
+
+		// blank ip means the node which answered
+		if ip == "" {
+			ip, _, _ = net.SplitHostPort(nodeAddr(node))
+		}
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
+
+		for i := start; i <= end; i++ {
+			slots[i] = addr
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.slots = slots
+	for _, v := range slots {
+		r.addNode(v)
+	}
+	return nil
+}
+
+// addNode : conn of node at addr, created on first use. Needs write lock
+func (r *slotRouter) addNode(addr string) *conn {
+	if addr == "" {
+		return nil
+	}
+
+	c, ok := r.nodes[addr]
+	if !ok {
+		u := url.URL{Scheme: r.scheme, User: r.user, Host: addr}
+		c = createConn(u.String(), r.cfg).(*conn)
+		r.nodes[addr] = c
+	}
+	return c
+}
+
+func (r *slotRouter) node(addr string) *conn {
+	r.mu.RLock()
+	c, ok := r.nodes[addr]
+	r.mu.RUnlock()
+
+	if ok {
+		return c
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addNode(addr)
+}
+
+func (r *slotRouter) nodeList() []*conn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make([]*conn, 0, len(r.nodes))
+	for _, v := range r.nodes {
+		nodes = append(nodes, v)
+	}
+	return nodes
+}
+
+// getConn : conn of master owning slot of key
+func (r *slotRouter) getConn(key string) (redis.Conn, error) {
+	r.mu.RLock()
+	addr := r.slots[keySlot(key)]
+	c := r.nodes[addr]
+	r.mu.RUnlock()
+
+	if c == nil {
+		return nil, ErrClusterSlotNotCovered
+	}
+	return &slotConn{router: r, node: c}, nil
+}
+
+// allConn : conns of all masters
+func (r *slotRouter) allConn() []redis.Conn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := map[string]bool{}
+	conns := []redis.Conn{}
+	for _, v := range r.slots {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		conns = append(conns, r.nodes[v])
+	}
+	return conns
+}
+
+// moved : slot is served by addr from now on
+func (r *slotRouter) moved(slot int, addr string) {
+	r.mu.Lock()
+	r.slots[slot] = addr
+	r.addNode(addr)
+	r.mu.Unlock()
+
+	r.refreshAsync()
+}
+
+// Close closes the connection.
+func (s *slotConn) Close() error {
+	return s.node.Close()
+}
+
+// Err returns a non-nil value when the connection is not usable.
+func (s *slotConn) Err() error {
+	return s.node.Err()
+}
+
+// Do sends a command to the node owning slot, following MOVED/ASK redirects
+func (s *slotConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	node, ask := s.node, false
+
+	for i := 0; i <= defaultMaxRedirects; i++ {
+		var reply interface{}
+		var err error
+
+		if ask {
+			reply, err = doAsking(node, commandName, args...)
+		} else {
+			reply, err = node.Do(commandName, args...)
+		}
+
+		rd, ok := parseRedirect(err)
+		if !ok {
+			return reply, err
+		}
+
+		if !rd.ask {
+			s.router.moved(rd.slot, rd.addr)
+		}
+		node, ask = s.router.node(rd.addr), rd.ask
+	}
+
+	return nil, ErrClusterTooManyRedirects
+}
+
+// Send writes the command to the client's output buffer, redirects are not followed
+func (s *slotConn) Send(commandName string, args ...interface{}) error {
+	return s.node.Send(commandName, args...)
+}
+
+// Flush flushes the output buffer to the Redis server.
+func (s *slotConn) Flush() error {
+	return s.node.Flush()
+}
+
+// Receive receives a single reply from the Redis server
+func (s *slotConn) Receive() (interface{}, error) {
+	return s.node.Receive()
+}
+
+// doAsking : ASKING and command must be sent on the same connection
+func doAsking(node *conn, commandName string, args ...interface{}) (interface{}, error) {
+	pc := node.pool.Get()
+	defer pc.Close()
+
+	if err := pc.Send("ASKING"); err != nil {
+		return nil, err
+	}
+	return pc.Do(commandName, args...)
+}
+
+// parseRedirect : "MOVED 3999 127.0.0.1:6381" or "ASK 3999 127.0.0.1:6381"
+func parseRedirect(err error) (*redirect, bool) {
+	e, ok := err.(redis.Error)
+	if !ok {
+		return nil, false
+	}
+
+	f := strings.Fields(string(e))
+	if len(f) != 3 || (f[0] != "MOVED" && f[0] != "ASK") {
+		return nil, false
+	}
+
+	slot, serr := strconv.Atoi(f[1])
+	if serr != nil {
+		return nil, false
+	}
+	return &redirect{ask: f[0] == "ASK", slot: slot, addr: f[2]}, true
+}
+
+func nodeAddr(c *conn) string {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// keySlot : hash slot of key, only hash tag {...} is hashed when present
+func keySlot(key string) int {
+	if s := strings.IndexByte(key, '{'); s >= 0 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	return int(crc16(key)) % clusterSlots
+}
+
+// crc16 : CRC16-CCITT (XMODEM) as used by Redis Cluster
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
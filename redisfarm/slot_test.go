@@ -0,0 +1,316 @@
+package redisfarm
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSlotNode : fake Redis Cluster node speaking enough RESP for slot routing
+type testSlotNode struct {
+	ln      net.Listener
+	addr    string
+	mu      sync.Mutex
+	handler func(asking bool, args []string) interface{}
+}
+
+type testSlotError string
+
+func newTestSlotNode(t *testing.T) *testSlotNode {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := &testSlotNode{ln: ln, addr: ln.Addr().String()}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go n.serve(c)
+		}
+	}()
+	return n
+}
+
+func (n *testSlotNode) url() string {
+	return "redis://" + n.addr
+}
+
+func (n *testSlotNode) setHandler(fn func(asking bool, args []string) interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.handler = fn
+}
+
+func (n *testSlotNode) serve(c net.Conn) {
+	defer c.Close()
+
+	r, w := bufio.NewReader(c), bufio.NewWriter(c)
+	asking := false
+
+	for {
+		args, err := testReadCommand(r)
+		if err != nil {
+			return
+		}
+
+		var reply interface{}
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			reply = "PONG"
+		case "ASKING":
+			asking, reply = true, "OK"
+		default:
+			n.mu.Lock()
+			reply = n.handler(asking, args)
+			n.mu.Unlock()
+			asking = false
+		}
+
+		testWriteReply(w, reply)
+		w.Flush()
+	}
+}
+
+func testReadCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSuffix(arg, "\r\n"))
+	}
+	return args, nil
+}
+
+func testWriteReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case string:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case testSlotError:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case []byte:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, e := range v {
+			testWriteReply(w, e)
+		}
+	}
+}
+
+// testSlots : CLUSTER SLOTS reply for ranges, each range is {start, end, node}
+func testSlots(ranges ...[]interface{}) []interface{} {
+	reply := []interface{}{}
+	for _, v := range ranges {
+		host, port, _ := net.SplitHostPort(v[2].(*testSlotNode).addr)
+		p, _ := strconv.Atoi(port)
+		reply = append(reply, []interface{}{v[0], v[1], []interface{}{[]byte(host), p, []byte("id")}})
+	}
+	return reply
+}
+
+// testSlotHandler : serves CLUSTER SLOTS from slots and GET with value
+func testSlotHandler(slots func() []interface{}, get func(asking bool, key string) interface{}) func(bool, []string) interface{} {
+	return func(asking bool, args []string) interface{} {
+		switch strings.ToUpper(args[0]) {
+		case "CLUSTER":
+			return slots()
+		case "GET":
+			return get(asking, args[1])
+		}
+		return testSlotError("ERR unknown command")
+	}
+}
+
+func TestKeySlot(t *testing.T) {
+	cases := map[string]int{
+		"foo":                  12182,
+		"123456789":            12739,
+		"{user1000}.following": keySlot("{user1000}.followers"),
+		"foo{}{bar}":           keySlot("foo{}{bar}"),
+		"{user1000}":           keySlot("user1000"),
+	}
+
+	for k, v := range cases {
+		if got := keySlot(k); got != v {
+			t.Errorf("Error: TestKeySlot %s Got %d, Want %d", k, got, v)
+		}
+	}
+}
+
+func TestClusterModeMoved(t *testing.T) {
+	a, b := newTestSlotNode(t), newTestSlotNode(t)
+	defer a.ln.Close()
+	defer b.ln.Close()
+
+	slot := keySlot("foo")
+	moved := int32(0)
+
+	slots := func() []interface{} {
+		if atomic.LoadInt32(&moved) == 1 {
+			return testSlots([]interface{}{0, slot - 1, a}, []interface{}{slot, slot, b}, []interface{}{slot + 1, clusterSlots - 1, a})
+		}
+		return testSlots([]interface{}{0, clusterSlots - 1, a})
+	}
+
+	a.setHandler(testSlotHandler(slots, func(bool, string) interface{} {
+		atomic.StoreInt32(&moved, 1)
+		return testSlotError(fmt.Sprintf("MOVED %d %s", slot, b.addr))
+	}))
+	b.setHandler(testSlotHandler(slots, func(bool, string) interface{} {
+		return []byte("b")
+	}))
+
+	cl, err := NewClusterBuilder().SetServers([]string{a.url()}).SetClusterMode(true).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := cl.GetConn("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.Do("GET", "foo")
+	if err != nil || string(r.([]byte)) != "b" {
+		t.Errorf("Error: TestClusterModeMoved Got %v %v, Want reply of node b", r, err)
+		return
+	}
+
+	// slot map is updated on MOVED
+	c, _ = cl.GetConn("foo")
+	if c.(*slotConn).node.url != b.url() {
+		t.Errorf("Error: TestClusterModeMoved Got %s, Want %s", c.(*slotConn).node.url, b.url())
+	}
+}
+
+func TestClusterModeAsk(t *testing.T) {
+	a, b := newTestSlotNode(t), newTestSlotNode(t)
+	defer a.ln.Close()
+	defer b.ln.Close()
+
+	slot := keySlot("foo")
+	slots := func() []interface{} {
+		return testSlots([]interface{}{0, clusterSlots - 1, a})
+	}
+
+	a.setHandler(testSlotHandler(slots, func(bool, string) interface{} {
+		return testSlotError(fmt.Sprintf("ASK %d %s", slot, b.addr))
+	}))
+	b.setHandler(testSlotHandler(slots, func(asking bool, key string) interface{} {
+		if !asking {
+			return testSlotError(fmt.Sprintf("MOVED %d %s", slot, a.addr))
+		}
+		return []byte("b")
+	}))
+
+	cl, err := NewClusterBuilder().SetServers([]string{a.url()}).SetClusterMode(true).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := cl.GetConn("foo")
+	r, err := c.Do("GET", "foo")
+	if err != nil || string(r.([]byte)) != "b" {
+		t.Errorf("Error: TestClusterModeAsk Got %v %v, Want reply of node b", r, err)
+		return
+	}
+
+	// slot map is not updated on ASK
+	c, _ = cl.GetConn("foo")
+	if c.(*slotConn).node.url != a.url() {
+		t.Errorf("Error: TestClusterModeAsk Got %s, Want %s", c.(*slotConn).node.url, a.url())
+	}
+}
+
+func TestClusterModeAllConn(t *testing.T) {
+	a, b := newTestSlotNode(t), newTestSlotNode(t)
+	defer a.ln.Close()
+	defer b.ln.Close()
+
+	slots := func() []interface{} {
+		return testSlots([]interface{}{0, 8191, a}, []interface{}{8192, clusterSlots - 1, b})
+	}
+	a.setHandler(testSlotHandler(slots, nil))
+
+	cl, err := NewClusterBuilder().SetServers([]string{a.url()}).SetClusterMode(true).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conns, err := cl.AllConn()
+	if err != nil || len(conns) != 2 {
+		t.Errorf("Error: TestClusterModeAllConn Got %d conns %v, Want conn of both masters", len(conns), err)
+	}
+}
+
+func TestClusterModeHealthCheck(t *testing.T) {
+	a := newTestSlotNode(t)
+	defer a.ln.Close()
+
+	a.setHandler(testSlotHandler(func() []interface{} {
+		return testSlots([]interface{}{0, clusterSlots - 1, a})
+	}, nil))
+
+	_, err := NewClusterBuilder().SetServers([]string{a.url()}).SetClusterMode(true).SetHealthCheck(time.Second, 2).Build()
+	if err != ErrClusterModeHealthCheck {
+		t.Errorf("Error: TestClusterModeHealthCheck Got %v, Want %v", err, ErrClusterModeHealthCheck)
+	}
+
+	_, err = NewClusterBuilder().SetServers([]string{a.url()}).SetClusterMode(true).SetHealthHandler(func(string, bool) {}).Build()
+	if err != ErrClusterModeHealthCheck {
+		t.Errorf("Error: TestClusterModeHealthCheck Got %v, Want %v", err, ErrClusterModeHealthCheck)
+	}
+}
+
+func TestFarmClusterMode(t *testing.T) {
+	a := newTestSlotNode(t)
+	defer a.ln.Close()
+
+	a.setHandler(testSlotHandler(func() []interface{} {
+		return testSlots([]interface{}{0, clusterSlots - 1, a})
+	}, nil))
+
+	slotted, err := NewClusterBuilder().SetServers([]string{a.url()}).SetClusterMode(true).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := NewClusterBuilder().SetServers([]string{a.url()}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ := NewBuilder().SetCluster([]*Cluster{plain}).Build()
+	if f.ClusterMode() {
+		t.Errorf("Error: TestFarmClusterMode Got %v, Want %v", true, false)
+	}
+
+	f, _ = NewBuilder().SetCluster([]*Cluster{plain, slotted}).Build()
+	if !f.ClusterMode() {
+		t.Errorf("Error: TestFarmClusterMode Got %v, Want %v", false, true)
+	}
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+
+	gopkgcache "github.com/alokic/gopkg/cache"
+	"github.com/alokic/gopkg/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+)
+
+// observer emits a span for every cache operation.
+type observer struct {
+	serviceName string
+}
+
+// NewObserver returns a cache observer tracing every operation with Datadog.
+// Span of an operation is a child of the span in its context, and carries
+// hits, misses and stale writes of the operation as tags.
+func NewObserver(serviceName string) gopkgcache.Observer {
+	return &observer{serviceName: serviceName}
+}
+
+// Start starts span of op.
+func (o *observer) Start(ctx context.Context, op string) (context.Context, gopkgcache.Done) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "cache.command",
+		tracer.SpanType(ext.AppTypeCache),
+		tracer.ServiceName(o.serviceName),
+		tracer.ResourceName(op),
+	)
+
+	return ctx, func(err error) {
+		span.Finish(tracer.WithError(err))
+	}
+}
+
+// Hit sets keys found by op on its span.
+func (o *observer) Hit(ctx context.Context, op string, n int) {
+	setTag(ctx, "cache.hits", n)
+}
+
+// Miss sets keys not found by op on its span.
+func (o *observer) Miss(ctx context.Context, op string, n int) {
+	setTag(ctx, "cache.misses", n)
+}
+
+// StaleWrite sets writes refused by op on its span.
+func (o *observer) StaleWrite(ctx context.Context, op string, n int) {
+	setTag(ctx, "cache.stale_writes", n)
+}
+
+func setTag(ctx context.Context, key string, value interface{}) {
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		span.SetTag(key, value)
+	}
+}
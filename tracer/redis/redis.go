@@ -1,83 +1,126 @@
 package redis
 
 import (
+	"context"
+	"fmt"
 	"time"
 
+	gopkgredis "github.com/alokic/gopkg/redis"
+	garyburdredis "github.com/garyburd/redigo/redis"
 	"github.com/gomodule/redigo/redis"
 	redigotrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/garyburd/redigo"
 )
 
-type poolConfig struct {
-	maxIdleConns   int
-	maxActiveConns int
-	idleTimeout    int // seconds
+// PoolOption is an option of the pool built by redis.NewRedisPool.
+type PoolOption = gopkgredis.PoolOption
+
+// tracedConn is the conn of the Datadog contrib tracer, which also supports
+// redis.DoWithTimeout and redis.ReceiveWithTimeout.
+type tracedConn struct {
+	redigotrace.Conn
 }
 
-//
-type PoolOption func(*poolConfig)
+// timeoutConn sends every Do with timeout.
+type timeoutConn struct {
+	garyburdredis.Conn
+	timeout time.Duration
+}
 
-// NewRedisPool returns a pool of redis connections.
+// NewRedisPool returns a pool of redis connections traced with Datadog.
+// It is redis.NewRedisPool with WithTracing option.
 func NewRedisPool(url, serviceName string, opts ...interface{}) *redis.Pool {
-	dialOpts, cfg := parsePoolOptions(opts)
-
-	return &redis.Pool{
-		MaxIdle:     cfg.maxIdleConns,
-		MaxActive:   cfg.maxActiveConns,
-		Wait:        true,
-		IdleTimeout: time.Duration(cfg.idleTimeout) * time.Second,
-
-		Dial: func() (redis.Conn, error) {
-			dialOpts = append(dialOpts, redigotrace.WithServiceName(serviceName))
-			return redigotrace.DialURL(url, dialOpts...)
-		},
-
-		TestOnBorrow: func(c redis.Conn, t time.Time) error {
-			_, err := c.Do("PING")
-			return err
-		},
-	}
+	o := make([]interface{}, 0, len(opts)+1)
+	o = append(o, opts...)
+
+	return gopkgredis.NewRedisPool(url, append(o, WithTracing(serviceName))...)
+}
+
+// WithTracing provides option to dial connections of redis.NewRedisPool with
+// the Datadog contrib tracer. Dial options of the pool must be garyburd/redigo
+// DialOption or contrib DialOption. When the last argument of Do is a
+// context.Context, the span is a child of the span in it. It is a dialer, so
+// a pool also given redis.WithDialer fails to dial with redis.ErrDialerConflict.
+func WithTracing(serviceName string) PoolOption {
+	return gopkgredis.WithDialer(func(url string, opts ...interface{}) (redis.Conn, error) {
+		dialOpts := make([]interface{}, 0, len(opts)+1)
+
+		for _, opt := range opts {
+			switch opt.(type) {
+			case garyburdredis.DialOption, redigotrace.DialOption:
+				dialOpts = append(dialOpts, opt)
+			default:
+				return nil, fmt.Errorf("Unknown traced redis pool option %T", opt)
+			}
+		}
+
+		c, err := redigotrace.DialURL(url, append(dialOpts, redigotrace.WithServiceName(serviceName))...)
+		if err != nil {
+			return nil, err
+		}
+		return tracedConn{c.(redigotrace.Conn)}, nil
+	})
 }
 
 // WithMaxIdleConns provides option to set maxIdleConns in connection pool.
 func WithMaxIdleConns(count int) PoolOption {
-	return func(cfg *poolConfig) {
-		cfg.maxIdleConns = count
-	}
+	return gopkgredis.WithMaxIdleConns(count)
 }
 
 // WithMaxActiveConns provides option to set maxActiveConns in connection pool.
 func WithMaxActiveConns(count int) PoolOption {
-	return func(cfg *poolConfig) {
-		cfg.maxActiveConns = count
-	}
+	return gopkgredis.WithMaxActiveConns(count)
 }
 
 // WithIdleTimeout provides option to set idleTimeout in connection pool.
 func WithIdleTimeout(seconds int) PoolOption {
-	return func(cfg *poolConfig) {
-		cfg.idleTimeout = seconds
-	}
+	return gopkgredis.WithIdleTimeout(seconds)
 }
 
-func parsePoolOptions(options ...interface{}) ([]interface{}, *poolConfig) {
-	var dialOpts []interface{}
+// WithSentinel provides option to dial the master of a sentinel monitored
+// service. Master is resolved again after failover.
+func WithSentinel(s *gopkgredis.Sentinel) PoolOption {
+	return gopkgredis.WithSentinel(s)
+}
+
+// WithReplicaReads provides option to dial replicas of the sentinel monitored
+// service instead of master, for read-only traffic. Needs WithSentinel.
+func WithReplicaReads() PoolOption {
+	return gopkgredis.WithReplicaReads()
+}
+
+// Do sends a command to the server and emits a span for it.
+func (tc tracedConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return tc.Conn.Do(commandName, withContext(args)...)
+}
+
+// DoWithTimeout sends a command to the server with timeout and emits a span for it.
+func (tc tracedConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	c := tc.Conn
+	c.Conn = timeoutConn{Conn: tc.Conn.Conn, timeout: timeout}
 
-	cfg := new(poolConfig)
-	redifPoolDefaults(cfg)
+	return c.Do(commandName, withContext(args)...)
+}
+
+// ReceiveWithTimeout receives a reply from the server with timeout.
+func (tc tracedConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return garyburdredis.ReceiveWithTimeout(tc.Conn.Conn, timeout)
+}
 
-	for _, opt := range options {
-		switch o := opt.(type) {
-		case PoolOption:
-			o(cfg)
-		default:
-			dialOpts = append(dialOpts, o)
+// Do sends a command to the server with timeout.
+func (c timeoutConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return garyburdredis.DoWithTimeout(c.Conn, c.timeout, commandName, args...)
+}
+
+// withContext appends context.Background to args without one, as the contrib
+// tracer cannot start a span from a nil context.
+func withContext(args []interface{}) []interface{} {
+	if n := len(args); n > 0 {
+		if _, ok := args[n-1].(context.Context); ok {
+			return args
 		}
 	}
-	return dialOpts, cfg
-}
 
-func redifPoolDefaults(cfg *poolConfig) {
-	cfg.maxIdleConns = 10
-	cfg.maxActiveConns = 10
-	cfg.idleTimeout = 240
+	a := make([]interface{}, 0, len(args)+1)
+	a = append(a, args...)
+	return append(a, context.Background())
 }
@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	gopkgredis "github.com/alokic/gopkg/redis"
+	"github.com/gomodule/redigo/redis"
+)
+
+var (
+	testServer = "redis://localhost:6379"
+)
+
+// testSentinel : fake sentinel which knows localhost:6379 as master
+func testSentinel(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(c net.Conn) {
+				defer c.Close()
+				r := bufio.NewReader(c)
+
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					var n int
+					fmt.Sscanf(line, "*%d", &n)
+					args := make([]string, 0, n)
+					for i := 0; i < n; i++ {
+						r.ReadString('\n')
+						arg, _ := r.ReadString('\n')
+						args = append(args, strings.TrimSpace(arg))
+					}
+
+					if len(args) > 1 && strings.ToLower(args[1]) == "get-master-addr-by-name" {
+						fmt.Fprint(c, "*2\r\n$9\r\n127.0.0.1\r\n$4\r\n6379\r\n")
+					}
+				}
+			}(c)
+		}
+	}()
+
+	t.Cleanup(func() { ln.Close() })
+	return ln.Addr().String()
+}
+
+func TestPoolTracing(t *testing.T) {
+	s := gopkgredis.NewSentinel("master", testSentinel(t))
+
+	p := NewRedisPool("redis://master:6379", "test", WithSentinel(s), WithMaxIdleConns(2))
+	defer p.Close()
+
+	if p.MaxIdle != 2 {
+		t.Errorf("Error: TestPoolTracing Got %d, Want %d", p.MaxIdle, 2)
+	}
+
+	c, err := p.Dial()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer c.Close()
+
+	if _, ok := c.(tracedConn); !ok {
+		t.Errorf("Error: TestPoolTracing Got %T, Want %T", c, tracedConn{})
+	}
+
+	if _, err := c.Do("PING"); err != nil {
+		t.Errorf("Error: TestPoolTracing Got %v, Want nil", err)
+	}
+}
+
+func TestPoolUnknownOption(t *testing.T) {
+	p := NewRedisPool(testServer, "test", "unknown")
+	defer p.Close()
+
+	if _, err := p.Dial(); err == nil {
+		t.Errorf("Error: TestPoolUnknownOption Got %v, Want error", err)
+	}
+}
+
+func TestPoolDialerConflict(t *testing.T) {
+	p := NewRedisPool(testServer, "test", gopkgredis.WithDialer(func(url string, opts ...interface{}) (redis.Conn, error) {
+		return redis.DialURL(url)
+	}))
+	defer p.Close()
+
+	if _, err := p.Dial(); err != gopkgredis.ErrDialerConflict {
+		t.Errorf("Error: TestPoolDialerConflict Got %v, Want %v", err, gopkgredis.ErrDialerConflict)
+	}
+}
@@ -50,3 +50,10 @@ type SpanContext = ddtrace.SpanContext
 func StartSpanFromContext(ctx context.Context, operationName string, opts ...StartSpanOption) (Span, context.Context) {
 	return tracer.StartSpanFromContext(ctx, operationName, opts...)
 }
+
+// SpanFromContext returns the span contained in the given context. A second return
+// value indicates if a span was found in the context. If no span is found, a no-op
+// span is returned.
+func SpanFromContext(ctx context.Context) (Span, bool) {
+	return tracer.SpanFromContext(ctx)
+}